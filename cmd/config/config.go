@@ -1,8 +1,192 @@
 package config
 
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	TokenModeOpaque = "opaque"
+	TokenModeJWT    = "jwt"
+
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmScrypt   = "scrypt"
+
+	StorageRAM    = "ram"
+	StorageSQLite = "sqlite"
+	StorageMySQL  = "mysql"
+)
+
 type AppConfig struct {
+	HTTP         HTTPConfig
+	Login        LoginConfig
+	Session      SessionConfig
+	Password     PasswordConfig
+	Identity     IdentityConfig
+	Verification VerificationConfig
+	Mail         MailConfig
+	Storage      StorageConfig
+}
+
+type HTTPConfig struct {
+	// RequestTimeout is deadline of request context, zero disables it
+	RequestTimeout time.Duration
+}
+
+type LoginConfig struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	FailuresWindow     time.Duration
+	LockDuration       time.Duration
+}
+
+type SessionConfig struct {
+	TTL             time.Duration
+	IdleTimeout     time.Duration
+	RefreshTTL      time.Duration
+	JanitorInterval time.Duration
+	Token           TokenConfig
+}
+
+type TokenConfig struct {
+	// Mode is TokenModeOpaque or TokenModeJWT
+	Mode string
+	// JWTAlgorithm is HS256 or RS256
+	JWTAlgorithm string
+	// JWTKeyFile keeps HS256 secret or RS256 private key in PEM
+	JWTKeyFile string
+}
+
+type PasswordConfig struct {
+	ResetTokenTTL time.Duration
+	// Algorithm for new hashes is one of PasswordAlgorithm*, hashes of others are upgraded on login
+	Algorithm  string
+	BcryptCost int
+	// Pepper is server side secret mixed into every password, changing it invalidates all passwords.
+	// Hashes made before pepper was set keep working and get peppered on login
+	Pepper string
+}
+
+// IdentityConfig defaults are loose enough for existing accounts,
+// production setup is expected to tighten them
+type IdentityConfig struct {
+	MinPasswordLength int
+	PasswordClasses   int
+	// CommonPasswordsFile is denylist with one password per line, empty disables it
+	CommonPasswordsFile string
+
+	MinUsernameLength int
+	MaxUsernameLength int
+	UsernamePattern   string
+}
+
+type VerificationConfig struct {
+	TokenTTL       time.Duration
+	ResendInterval time.Duration
+	// UnverifiedReadOnly forbids unverified users to write, they still can log in
+	UnverifiedReadOnly bool
+}
+
+// MailConfig without SMTPAddr keeps mail in memory, it is for local runs only
+type MailConfig struct {
+	SMTPAddr     string
+	From         string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// StorageConfig selects where users, sessions, articles, comments and follows are kept.
+// Login attempts and user tokens are always in memory
+type StorageConfig struct {
+	// Backend is one of Storage*
+	Backend string
+	// SQLitePath is database file, it is created if missing
+	SQLitePath string
+	MySQLDSN   string
+	// AutoMigrate applies pending migrations on start, otherwise they are applied with "migrate up" command
+	AutoMigrate bool
 }
 
 func InitConfig() *AppConfig {
-	return &AppConfig{}
+	return &AppConfig{
+		HTTP: HTTPConfig{
+			RequestTimeout: getEnvDuration("RWA_REQUEST_TIMEOUT", 30*time.Second),
+		},
+		Login: LoginConfig{
+			MaxAccountFailures: 5,
+			MaxIPFailures:      20,
+			FailuresWindow:     15 * time.Minute,
+			LockDuration:       15 * time.Minute,
+		},
+		Session: SessionConfig{
+			TTL:             7 * 24 * time.Hour,
+			IdleTimeout:     24 * time.Hour,
+			RefreshTTL:      30 * 24 * time.Hour,
+			JanitorInterval: time.Minute,
+			Token: TokenConfig{
+				Mode:         getEnv("RWA_TOKEN_MODE", TokenModeOpaque),
+				JWTAlgorithm: getEnv("RWA_JWT_ALGORITHM", "HS256"),
+				JWTKeyFile:   os.Getenv("RWA_JWT_KEY_FILE"),
+			},
+		},
+		Password: PasswordConfig{
+			ResetTokenTTL: time.Hour,
+			Algorithm:     getEnv("RWA_PASSWORD_ALGORITHM", PasswordAlgorithmArgon2id),
+			BcryptCost:    12,
+			Pepper:        os.Getenv("RWA_PASSWORD_PEPPER"),
+		},
+		Identity: IdentityConfig{
+			MinPasswordLength:   getEnvInt("RWA_MIN_PASSWORD_LENGTH", 4),
+			PasswordClasses:     getEnvInt("RWA_PASSWORD_CLASSES", 1),
+			CommonPasswordsFile: os.Getenv("RWA_COMMON_PASSWORDS_FILE"),
+			MinUsernameLength:   3,
+			MaxUsernameLength:   32,
+			UsernamePattern:     `^[\p{L}\p{N}_.-]+$`,
+		},
+		Verification: VerificationConfig{
+			TokenTTL:           24 * time.Hour,
+			ResendInterval:     time.Minute,
+			UnverifiedReadOnly: os.Getenv("RWA_UNVERIFIED_READ_ONLY") == "true",
+		},
+		Mail: MailConfig{
+			SMTPAddr:     os.Getenv("RWA_SMTP_ADDR"),
+			From:         getEnv("RWA_MAIL_FROM", "noreply@localhost"),
+			SMTPUsername: os.Getenv("RWA_SMTP_USERNAME"),
+			SMTPPassword: os.Getenv("RWA_SMTP_PASSWORD"),
+		},
+		Storage: StorageConfig{
+			Backend:     getEnv("RWA_STORAGE", StorageRAM),
+			SQLitePath:  getEnv("RWA_SQLITE_PATH", "rwa.db"),
+			MySQLDSN:    os.Getenv("RWA_MYSQL_DSN"),
+			AutoMigrate: getEnv("RWA_AUTO_MIGRATE", "true") == "true",
+		},
+	}
+}
+
+func getEnv(key string, def string) string {
+	if val, ok := os.LookupEnv(key); ok {
+		return val
+	}
+
+	return def
+}
+
+func getEnvInt(key string, def int) int {
+	val, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+
+	return val
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	val, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+
+	return val
 }
@@ -1,16 +1,51 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"rwa/cmd/config"
 	"rwa/internal/realworld"
+	"syscall"
+	"time"
 )
 
-// сюда код писать не надо
+const shutdownTimeout = 10 * time.Second
 
 func main() {
+	cfg := config.InitConfig()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	addr := ":8080"
-	h := realworld.GetApp()
-	fmt.Println("start server at", addr)
-	http.ListenAndServe(addr, h)
+	app := realworld.NewApp(cfg)
+	defer app.Close()
+
+	server := &http.Server{Addr: addr, Handler: app}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		fmt.Println("start server at", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Println(err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	server.Shutdown(shutdownCtx)
 }
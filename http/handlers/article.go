@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"rwa/internal/models"
 	"rwa/internal/services"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
 )
 
 type ArticleHandler struct {
@@ -41,14 +48,14 @@ func (h *ArticleHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.us.GetUserById(uId)
+	user, err := h.us.GetUserById(r.Context(), uId)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	article, err := h.as.CreateArticle(*user, articleInfo)
+	article, err := h.as.CreateArticle(r.Context(), *user, articleInfo)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
@@ -63,43 +70,267 @@ func (h *ArticleHandler) Create(w http.ResponseWriter, r *http.Request) {
 type ArticlesResponse struct {
 	Articles     []*models.Article `json:"articles"`
 	ArticleCount int               `json:"articlesCount"`
+	NextCursor   string            `json:"nextCursor,omitempty"`
+}
+
+func newArticlesResponse(articles []*models.Article, total int, page models.Page) ArticlesResponse {
+	res := ArticlesResponse{
+		Articles:     articles,
+		ArticleCount: total,
+	}
+
+	// full page means there could be more articles after the last one
+	if len(articles) != 0 && len(articles) == page.Limit {
+		res.NextCursor = encodeCursor(articles[len(articles)-1].Cursor())
+	}
+
+	return res
 }
 
 func (h *ArticleHandler) Get(w http.ResponseWriter, r *http.Request) {
 	vals := r.URL.Query()
-	tags := vals["tag"]
-	username := vals.Get("author")
 
-	var articles []*models.Article
-	var err error
+	page, err := getPagination(vals)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	filter, err := h.getFilter(r.Context(), vals)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	// search results are ordered by relevance, cursor can't point into them
+	if filter.Query != "" && page.After != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cursor can't be used with search query"))
+		return
+	}
+
+	articles, total, err := h.as.GetArticles(r.Context(), h.viewer(r), filter, page)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	res := newArticlesResponse(articles, total, page)
+	if filter.Query != "" {
+		res.NextCursor = ""
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
 
-	if username != "" {
-		user, err := h.us.GetUserByUsername(username)
+func (h *ArticleHandler) getFilter(ctx context.Context, vals url.Values) (models.ArticleFilter, error) {
+	filter := models.ArticleFilter{
+		Query:       strings.TrimSpace(vals.Get("q")),
+		Tags:        vals["tag"],
+		TagMode:     models.TagModeAny,
+		ExcludeTags: vals["excludeTag"],
+	}
+
+	switch mode := models.TagMode(vals.Get("tagMode")); mode {
+	case "", models.TagModeAny:
+	case models.TagModeAll:
+		filter.TagMode = mode
+	default:
+		return filter, errors.New("tagMode must be all or any")
+	}
+
+	if username := vals.Get("author"); username != "" {
+		user, err := h.us.GetUserByUsername(ctx, username)
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte("Not found"))
-			return
+			return filter, err
 		}
+		filter.AuthorId = user.ID
+	}
 
-		articles, err = h.as.GetAllByUser(*user, tags)
+	if username := vals.Get("favorited"); username != "" {
+		user, err := h.us.GetUserByUsername(ctx, username)
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte(err.Error()))
-			return
+			return filter, err
 		}
-	} else {
-		articles, err = h.as.GetAll(tags)
+		filter.FavoritedBy = user.ID
+	}
+
+	bounds := []struct {
+		param string
+		dst   *time.Time
+	}{
+		{"createdFrom", &filter.CreatedFrom},
+		{"createdTo", &filter.CreatedTo},
+		{"updatedFrom", &filter.UpdatedFrom},
+		{"updatedTo", &filter.UpdatedTo},
+	}
+	for _, b := range bounds {
+		v := vals.Get(b.param)
+		if v == "" {
+			continue
+		}
+
+		t, err := time.Parse(timeFormat, v)
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte(err.Error()))
-			return
+			return filter, errors.New(b.param + " must be in RFC3339 format")
 		}
+		*b.dst = t
 	}
 
-	res := ArticlesResponse{
-		Articles:     articles,
-		ArticleCount: len(articles),
+	return filter, nil
+}
+
+func (h *ArticleHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	page, err := getPagination(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	user, err := h.currentUser(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	articles, total, err := h.as.GetFeed(r.Context(), *user, page)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	res := newArticlesResponse(articles, total, page)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *ArticleHandler) GetBySlug(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+
+	article, err := h.as.GetByTitle(r.Context(), h.viewer(r), slug)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	res := map[string]interface{}{"article": article}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+type ArticleUpdateRequest struct {
+	Article models.ArticleInfo `json:"article"`
+}
+
+func (h *ArticleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	articleReq := ArticleUpdateRequest{}
+	err := json.NewDecoder(r.Body).Decode(&articleReq)
+	if err != nil {
+		badJsonError(w)
+		return
+	}
+
+	user, err := h.currentUser(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	article, err := h.as.GetByTitle(r.Context(), user, mux.Vars(r)["slug"])
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	updated, err := h.as.UpdateArticle(r.Context(), *user, *article, articleReq.Article)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	res := map[string]interface{}{"article": updated}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *ArticleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := h.currentUser(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	article, err := h.as.GetByTitle(r.Context(), user, mux.Vars(r)["slug"])
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	err = h.as.DeleteArticle(r.Context(), *user, *article)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+func (h *ArticleHandler) Favorite(w http.ResponseWriter, r *http.Request) {
+	h.toggleFavorite(w, r, h.as.Favorite)
+}
+
+func (h *ArticleHandler) Unfavorite(w http.ResponseWriter, r *http.Request) {
+	h.toggleFavorite(w, r, h.as.Unfavorite)
+}
+
+type favoriteAction func(context.Context, models.User, models.Article) (*models.Article, error)
+
+func (h *ArticleHandler) toggleFavorite(w http.ResponseWriter, r *http.Request, action favoriteAction) {
+	user, err := h.currentUser(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	article, err := h.as.GetByTitle(r.Context(), user, mux.Vars(r)["slug"])
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	article, err = action(r.Context(), *user, *article)
+	if err != nil {
+		serviceError(w, err)
+		return
 	}
+
+	res := map[string]interface{}{"article": article}
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(res)
 }
+
+// viewer returns authorized user or nil for anonymous requests
+func (h *ArticleHandler) viewer(r *http.Request) *models.User {
+	user, err := h.currentUser(r)
+	if err != nil {
+		return nil
+	}
+
+	return user
+}
+
+func (h *ArticleHandler) currentUser(r *http.Request) (*models.User, error) {
+	uId, err := GetUserIdFromRequestCtx(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.us.GetUserById(r.Context(), uId)
+}
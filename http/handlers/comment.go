@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"rwa/internal/models"
+	"rwa/internal/services"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type CommentHandler struct {
+	cs *services.CommentService
+	as *services.ArticleService
+	us *services.UserService
+}
+
+func NewCommentHandler(cs *services.CommentService, as *services.ArticleService, us *services.UserService) *CommentHandler {
+	return &CommentHandler{
+		cs: cs,
+		as: as,
+		us: us,
+	}
+}
+
+type CommentCreateRequest struct {
+	Comment models.CommentInfo `json:"comment"`
+}
+
+type CommentsResponse struct {
+	Comments []*models.Comment `json:"comments"`
+}
+
+func (h *CommentHandler) Get(w http.ResponseWriter, r *http.Request) {
+	viewer := h.viewer(r)
+
+	article, err := h.as.GetByTitle(r.Context(), viewer, mux.Vars(r)["slug"])
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	comments, err := h.cs.GetAllByArticle(r.Context(), viewer, *article)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	res := CommentsResponse{
+		Comments: comments,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *CommentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	commentReq := CommentCreateRequest{}
+	err := json.NewDecoder(r.Body).Decode(&commentReq)
+	if err != nil {
+		badJsonError(w)
+		return
+	}
+
+	user, err := h.currentUser(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	article, err := h.as.GetByTitle(r.Context(), user, mux.Vars(r)["slug"])
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	comment, err := h.cs.CreateComment(r.Context(), *user, *article, commentReq.Comment)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	res := map[string]interface{}{"comment": comment}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Bad comment id"))
+		return
+	}
+
+	user, err := h.currentUser(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	article, err := h.as.GetByTitle(r.Context(), user, vars["slug"])
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	comment, err := h.cs.GetById(r.Context(), *article, id)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	err = h.cs.DeleteComment(r.Context(), *user, *comment)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// viewer returns authorized user or nil for anonymous requests
+func (h *CommentHandler) viewer(r *http.Request) *models.User {
+	user, err := h.currentUser(r)
+	if err != nil {
+		return nil
+	}
+
+	return user
+}
+
+func (h *CommentHandler) currentUser(r *http.Request) (*models.User, error) {
+	uId, err := GetUserIdFromRequestCtx(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.us.GetUserById(r.Context(), uId)
+}
@@ -4,4 +4,7 @@ import "time"
 
 const (
 	timeFormat = time.RFC3339
+
+	defaultLimit = 20
+	maxLimit     = 100
 )
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"rwa/internal/models"
+	"rwa/internal/services"
+)
+
+type PasswordHandler struct {
+	ps *services.PasswordService
+	us *services.UserService
+}
+
+func NewPasswordHandler(ps *services.PasswordService, us *services.UserService) *PasswordHandler {
+	return &PasswordHandler{
+		ps: ps,
+		us: us,
+	}
+}
+
+type PasswordChangeRequest struct {
+	User models.PasswordChangeInfo `json:"user"`
+}
+
+type PasswordResetRequest struct {
+	User struct {
+		Email string `json:"email"`
+	} `json:"user"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (h *PasswordHandler) Change(w http.ResponseWriter, r *http.Request) {
+	changeReq := PasswordChangeRequest{}
+	err := json.NewDecoder(r.Body).Decode(&changeReq)
+	if err != nil {
+		badJsonError(w)
+		return
+	}
+
+	uId, err := GetUserIdFromRequestCtx(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	user, err := h.us.GetUserById(r.Context(), uId)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	sesId, err := GetSessionIdFromRequestCtx(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	updated, err := h.ps.ChangePassword(r.Context(), *user, sesId, changeReq.User)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	// current session and its tokens stay valid, other sessions are revoked
+	res := map[string]interface{}{
+		"user": updated,
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// RequestReset answers the same for known and unknown emails
+func (h *PasswordHandler) RequestReset(w http.ResponseWriter, r *http.Request) {
+	resetReq := PasswordResetRequest{}
+	err := json.NewDecoder(r.Body).Decode(&resetReq)
+	if err != nil {
+		badJsonError(w)
+		return
+	}
+
+	if resetReq.User.Email == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Email is empty"))
+		return
+	}
+
+	err = h.ps.RequestReset(r.Context(), resetReq.User.Email)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("OK"))
+}
+
+func (h *PasswordHandler) ConfirmReset(w http.ResponseWriter, r *http.Request) {
+	confirmReq := PasswordResetConfirmRequest{}
+	err := json.NewDecoder(r.Body).Decode(&confirmReq)
+	if err != nil {
+		badJsonError(w)
+		return
+	}
+
+	err = h.ps.ConfirmReset(r.Context(), confirmReq.Token, confirmReq.Password)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"rwa/internal/models"
+	"rwa/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ProfileHandler struct {
+	us *services.UserService
+}
+
+func NewProfileHandler(us *services.UserService) *ProfileHandler {
+	return &ProfileHandler{
+		us: us,
+	}
+}
+
+func (h *ProfileHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := h.us.GetUserByUsername(r.Context(), mux.Vars(r)["username"])
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	var viewer *models.User
+	if uId, err := GetUserIdFromRequestCtx(r); err == nil {
+		viewer, _ = h.us.GetUserById(r.Context(), uId)
+	}
+
+	profile, err := h.us.GetProfile(r.Context(), viewer, *user)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	res := map[string]interface{}{"profile": profile}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *ProfileHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	h.toggleFollow(w, r, h.us.Follow)
+}
+
+func (h *ProfileHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	h.toggleFollow(w, r, h.us.Unfollow)
+}
+
+type followAction func(ctx context.Context, follower models.User, followee models.User) error
+
+func (h *ProfileHandler) toggleFollow(w http.ResponseWriter, r *http.Request, action followAction) {
+	uId, err := GetUserIdFromRequestCtx(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	follower, err := h.us.GetUserById(r.Context(), uId)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	followee, err := h.us.GetUserByUsername(r.Context(), mux.Vars(r)["username"])
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	err = action(r.Context(), *follower, *followee)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	profile, err := h.us.GetProfile(r.Context(), follower, *followee)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	res := map[string]interface{}{"profile": profile}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"rwa/internal/models"
+	"rwa/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+type SessionHandler struct {
+	sm *services.SessionManager
+	us *services.UserService
+}
+
+func NewSessionHandler(sm *services.SessionManager, us *services.UserService) *SessionHandler {
+	return &SessionHandler{
+		sm: sm,
+		us: us,
+	}
+}
+
+type SessionResponse struct {
+	*models.Session
+	Current bool `json:"current"`
+}
+
+type SessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+func (h *SessionHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, err := h.currentUser(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	currentId, _ := GetSessionIdFromRequestCtx(r)
+
+	sessions, err := h.sm.GetAllByUser(r.Context(), *user)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	res := SessionsResponse{
+		Sessions: make([]SessionResponse, 0, len(sessions)),
+	}
+	for _, s := range sessions {
+		res.Sessions = append(res.Sessions, SessionResponse{
+			Session: s,
+			Current: s.ID == currentId,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *SessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	user, err := h.currentUser(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	err = h.sm.Revoke(r.Context(), *user, mux.Vars(r)["id"])
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+func (h *SessionHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	user, err := h.currentUser(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	err = h.sm.DeleteAllByUser(r.Context(), *user)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+func (h *SessionHandler) currentUser(r *http.Request) (*models.User, error) {
+	uId, err := GetUserIdFromRequestCtx(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.us.GetUserById(r.Context(), uId)
+}
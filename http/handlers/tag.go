@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"rwa/internal/services"
+)
+
+type TagHandler struct {
+	as *services.ArticleService
+}
+
+func NewTagHandler(as *services.ArticleService) *TagHandler {
+	return &TagHandler{
+		as: as,
+	}
+}
+
+func (h *TagHandler) Get(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.as.GetTags(r.Context())
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	var res interface{}
+	if r.URL.Query().Get("withCounts") == "true" {
+		res = map[string]interface{}{"tags": tags}
+	} else {
+		names := make([]string, 0, len(tags))
+		for _, t := range tags {
+			names = append(names, t.Tag)
+		}
+		res = map[string]interface{}{"tags": names}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
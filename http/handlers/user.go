@@ -2,20 +2,25 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"rwa/internal/models"
 	"rwa/internal/services"
 )
 
 type UserHandler struct {
-	us *services.UserService
-	sm *services.SessionManager
+	us   *services.UserService
+	sm   *services.SessionManager
+	auth *services.AuthService
+	vs   *services.VerificationService
 }
 
-func NewUserHandler(us *services.UserService, sesManager *services.SessionManager) *UserHandler {
+func NewUserHandler(us *services.UserService, sesManager *services.SessionManager, auth *services.AuthService, vs *services.VerificationService) *UserHandler {
 	return &UserHandler{
-		us: us,
-		sm: sesManager,
+		us:   us,
+		sm:   sesManager,
+		auth: auth,
+		vs:   vs,
 	}
 }
 
@@ -38,13 +43,17 @@ func (uh *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := uh.us.CreateUser(registerData.User)
+	user, err := uh.us.CreateUser(r.Context(), registerData.User)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(err.Error()))
+		serviceError(w, err)
 		return
 	}
 
+	// user can ask for another mail if this one is lost
+	if err := uh.vs.SendVerification(r.Context(), *user); err != nil {
+		fmt.Println("send verification:", err)
+	}
+
 	res := map[string]interface{}{
 		"user": user,
 	}
@@ -63,11 +72,12 @@ type LoginRequestData struct {
 }
 
 type LoginResponseData struct {
-	Email     string `json:"email"`
-	Username  string `json:"username"`
-	CreatedAt string `json:"createdAt"`
-	UpdatedAt string `json:"updatedAt"`
-	Token     string `json:"token"`
+	Email        string `json:"email"`
+	Username     string `json:"username"`
+	CreatedAt    string `json:"createdAt"`
+	UpdatedAt    string `json:"updatedAt"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
 }
 
 func (uh *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -84,16 +94,13 @@ func (uh *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := uh.us.GetByEmail(loginData.User.Email)
+	user, err := uh.auth.Login(r.Context(), loginData.User.Email, loginData.User.Password, GetClientIP(r))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		// TODO: ошибка может сообщить лишнее о системе
-		// следует явно управлять сообщениями ошибок во внешний мир
-		w.Write([]byte(err.Error()))
+		serviceError(w, err)
 		return
 	}
 
-	session, err := uh.sm.Create(*user)
+	_, tokens, err := uh.sm.Create(r.Context(), *user, clientInfo(r))
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
@@ -101,11 +108,12 @@ func (uh *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logDataRes := LoginResponseData{
-		Email:     user.Email,
-		Username:  user.Username,
-		CreatedAt: user.CreatedAt.Format(timeFormat),
-		UpdatedAt: user.UpdatedAt.Format(timeFormat),
-		Token:     session.GetSessionId(),
+		Email:        user.Email,
+		Username:     user.Username,
+		CreatedAt:    user.CreatedAt.Format(timeFormat),
+		UpdatedAt:    user.UpdatedAt.Format(timeFormat),
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}
 
 	res := map[string]interface{}{"user": logDataRes}
@@ -114,9 +122,43 @@ func (uh *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(res)
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (uh *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	refreshReq := RefreshRequest{}
+	err := json.NewDecoder(r.Body).Decode(&refreshReq)
+	if err != nil {
+		badJsonError(w)
+		return
+	}
+
+	if refreshReq.RefreshToken == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Refresh token is empty"))
+		return
+	}
+
+	_, tokens, err := uh.sm.Refresh(r.Context(), refreshReq.RefreshToken, clientInfo(r))
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
 func (uh *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	sesId := GetTokenFromRequest(r)
-	err := uh.sm.Delete(sesId)
+	sesId, err := GetSessionIdFromRequestCtx(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	err = uh.sm.Delete(r.Context(), sesId)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
@@ -135,7 +177,7 @@ func (uh *UserHandler) Info(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := uh.us.GetUserById(uId)
+	user, err := uh.us.GetUserById(r.Context(), uId)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
@@ -170,20 +212,25 @@ func (uh *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	data := reqData.User
 
-	user, err := uh.us.GetUserById(uId)
+	user, err := uh.us.GetUserById(r.Context(), uId)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	updatedUser, err := uh.us.UpdateUser(*user, data)
+	updatedUser, err := uh.us.UpdateUser(r.Context(), *user, data)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(err.Error()))
+		serviceError(w, err)
 		return
 	}
 
+	if updatedUser.Email != user.Email {
+		if err := uh.vs.SendVerification(r.Context(), *updatedUser); err != nil {
+			fmt.Println("send verification:", err)
+		}
+	}
+
 	res := map[string]interface{}{
 		"user": updatedUser,
 	}
@@ -1,21 +1,50 @@
 package handlers
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
+	"net/url"
+	"rwa/internal/models"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
-	UserCtxKey = "user_id"
-
 	TokenHeader = "Authorization"
 	TokenPrefix = "Token "
 )
 
+// ctxKey is unexported, so values set here can't collide with other packages
+type ctxKey int
+
+const (
+	userCtxKey ctxKey = iota
+	sessionCtxKey
+)
+
+// WithSession puts authorized user and session ids into ctx
+func WithSession(ctx context.Context, userId int64, sessionId string) context.Context {
+	ctx = context.WithValue(ctx, userCtxKey, userId)
+	return context.WithValue(ctx, sessionCtxKey, sessionId)
+}
+
+func UserIdFromContext(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(userCtxKey).(int64)
+	return id, ok
+}
+
+func SessionIdFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionCtxKey).(string)
+	return id, ok
+}
+
 func GetUserIdFromRequestCtx(r *http.Request) (int64, error) {
-	ctx := r.Context()
-	id, ok := ctx.Value(UserCtxKey).(int64)
+	id, ok := UserIdFromContext(r.Context())
 	if !ok {
 		return -1, errors.New("Not found user in request context")
 	}
@@ -23,6 +52,15 @@ func GetUserIdFromRequestCtx(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+func GetSessionIdFromRequestCtx(r *http.Request) (string, error) {
+	id, ok := SessionIdFromContext(r.Context())
+	if !ok {
+		return "", errors.New("Not found session in request context")
+	}
+
+	return id, nil
+}
+
 func GetTokenFromRequest(r *http.Request) string {
 	val := r.Header.Get(TokenHeader)
 	if val == "" || !strings.HasPrefix(val, TokenPrefix) {
@@ -32,7 +70,128 @@ func GetTokenFromRequest(r *http.Request) string {
 	return val[len(TokenPrefix):]
 }
 
+// GetClientIP takes address of direct peer, proxy headers are not trusted
+func GetClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func clientInfo(r *http.Request) models.ClientInfo {
+	return models.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IP:        GetClientIP(r),
+	}
+}
+
+func getPagination(vals url.Values) (models.Page, error) {
+	page := models.Page{Limit: defaultLimit}
+
+	if v := vals.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return page, errors.New("limit must be positive number")
+		}
+		page.Limit = min(limit, maxLimit)
+	}
+
+	if v := vals.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return page, errors.New("offset must be non-negative number")
+		}
+		page.Offset = offset
+	}
+
+	if v := vals.Get("cursor"); v != "" {
+		cursor, err := decodeCursor(v)
+		if err != nil {
+			return page, err
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
+
+// cursor is opaque for clients: base64 of "<createdAt unix nano>:<slug>"
+func encodeCursor(c models.ArticleCursor) string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.Slug
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*models.ArticleCursor, error) {
+	errBadCursor := errors.New("bad cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errBadCursor
+	}
+
+	ts, slug, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, errBadCursor
+	}
+
+	nano, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, errBadCursor
+	}
+
+	return &models.ArticleCursor{
+		CreatedAt: time.Unix(0, nano),
+		Slug:      slug,
+	}, nil
+}
+
 func badJsonError(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusBadRequest)
 	w.Write([]byte("Error json"))
 }
+
+// ContextError answers errors of done request context and tells whether err was one of them
+func ContextError(w http.ResponseWriter, err error) bool {
+	// client is gone, nobody reads the response
+	if errors.Is(err, context.Canceled) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("request timed out"))
+		return true
+	}
+
+	return false
+}
+
+func serviceError(w http.ResponseWriter, err error) {
+	if ContextError(w, err) {
+		return
+	}
+
+	var validationErrs models.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": validationErrs})
+		return
+	}
+
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, models.ErrPermissionDenied):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, models.ErrInvalidCredentials):
+		w.WriteHeader(http.StatusUnauthorized)
+	case errors.Is(err, models.ErrTooManyAttempts):
+		w.WriteHeader(http.StatusTooManyRequests)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	w.Write([]byte(err.Error()))
+}
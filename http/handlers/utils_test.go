@@ -0,0 +1,46 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rwa/http/handlers"
+	"rwa/internal/services"
+	"testing"
+	"time"
+)
+
+// ctxArticleRepository fails like database driver does once request context is done
+type ctxArticleRepository struct {
+	services.ArticleRepository
+}
+
+func (ctxArticleRepository) Tags(ctx context.Context) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return map[string]int{}, nil
+}
+
+func TestServiceErrorContextDone(t *testing.T) {
+	h := handlers.NewTagHandler(services.NewArticleService(ctxArticleRepository{}, nil, nil, nil))
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	w := httptest.NewRecorder()
+	h.Get(w, httptest.NewRequest(http.MethodGet, "/api/tags", nil).WithContext(expired))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expired deadline: want 503, have: %d %s", w.Code, w.Body)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	w = httptest.NewRecorder()
+	h.Get(w, httptest.NewRequest(http.MethodGet, "/api/tags", nil).WithContext(canceled))
+	if w.Body.Len() != 0 {
+		t.Fatalf("canceled request: want empty body, have: %s", w.Body)
+	}
+}
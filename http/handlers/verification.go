@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"rwa/internal/services"
+)
+
+type VerificationHandler struct {
+	vs *services.VerificationService
+	us *services.UserService
+}
+
+func NewVerificationHandler(vs *services.VerificationService, us *services.UserService) *VerificationHandler {
+	return &VerificationHandler{
+		vs: vs,
+		us: us,
+	}
+}
+
+type VerifyRequest struct {
+	Token string `json:"token"`
+}
+
+func (h *VerificationHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	verifyReq := VerifyRequest{}
+	err := json.NewDecoder(r.Body).Decode(&verifyReq)
+	if err != nil {
+		badJsonError(w)
+		return
+	}
+
+	user, err := h.vs.Verify(r.Context(), verifyReq.Token)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	res := map[string]interface{}{"user": user}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *VerificationHandler) Resend(w http.ResponseWriter, r *http.Request) {
+	uId, err := GetUserIdFromRequestCtx(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	user, err := h.us.GetUserById(r.Context(), uId)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	err = h.vs.Resend(r.Context(), *user)
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("OK"))
+}
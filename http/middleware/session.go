@@ -1,43 +1,92 @@
 package middleware
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"rwa/http/handlers"
+	"rwa/internal/models"
 	"rwa/internal/services"
 
 	"github.com/gorilla/mux"
 )
 
+var errNoToken = errors.New("No Auth")
+
 type SessionGuard struct {
 	sesManager *services.SessionManager
+	tokens     services.TokenStrategy
 }
 
-func NewSessionGuard(sesManager *services.SessionManager) *SessionGuard {
+func NewSessionGuard(sesManager *services.SessionManager, tokens services.TokenStrategy) *SessionGuard {
 	return &SessionGuard{
 		sesManager: sesManager,
+		tokens:     tokens,
 	}
 }
 
 func (sg *SessionGuard) GetAuthMiddleware() mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token := handlers.GetTokenFromRequest(r)
-			if token == "" {
+			session, err := sg.getSession(r)
+			if errors.Is(err, errNoToken) {
 				w.WriteHeader(http.StatusUnauthorized)
 				w.Write([]byte("No Auth"))
 				return
 			}
-
-			session, err := sg.sesManager.Get(token)
+			if err != nil && handlers.ContextError(w, err) {
+				return
+			}
 			if err != nil {
 				w.WriteHeader(http.StatusUnauthorized)
 				w.Write([]byte("No Auth - Bad Token"))
 				return
 			}
 
-			newContext := context.WithValue(r.Context(), handlers.UserCtxKey, session.UserId)
-			next.ServeHTTP(w, r.WithContext(newContext))
+			next.ServeHTTP(w, r.WithContext(handlers.WithSession(r.Context(), session.UserId, session.ID)))
+		})
+	}
+}
+
+// GetOptionalAuthMiddleware puts user into request context if valid token passed,
+// requests without token are served as anonymous
+func (sg *SessionGuard) GetOptionalAuthMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, err := sg.getSession(r)
+			if err != nil && handlers.ContextError(w, err) {
+				return
+			}
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(handlers.WithSession(r.Context(), session.UserId, session.ID)))
 		})
 	}
 }
+
+// getSession checks token from request and prolongs session it points to
+func (sg *SessionGuard) getSession(r *http.Request) (*models.Session, error) {
+	token := handlers.GetTokenFromRequest(r)
+	if token == "" {
+		return nil, errNoToken
+	}
+
+	sessionId, err := sg.tokens.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := sg.sesManager.Get(r.Context(), sessionId, token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sg.sesManager.Touch(r.Context(), *session)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
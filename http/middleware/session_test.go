@@ -0,0 +1,70 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rwa/http/handlers"
+	"rwa/http/middleware"
+	"rwa/internal/models"
+	"rwa/internal/services"
+	"rwa/pkg/clock"
+	"testing"
+	"time"
+)
+
+// ctxSessionRepository fails like database driver does once request context is done
+type ctxSessionRepository struct {
+	services.SessionRepository
+}
+
+func (ctxSessionRepository) Get(ctx context.Context, sessionId string) (*models.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, models.ErrNotFound
+}
+
+func TestAuthMiddlewareContextDone(t *testing.T) {
+	sm := services.NewSessionManager(ctxSessionRepository{}, nil, services.OpaqueTokens{}, clock.NewFake(time.Now()), services.SessionPolicy{})
+	sg := middleware.NewSessionGuard(sm, services.OpaqueTokens{})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("served"))
+	})
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for name, mw := range map[string]func(http.Handler) http.Handler{
+		"required": sg.GetAuthMiddleware(),
+		"optional": sg.GetOptionalAuthMiddleware(),
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+		r.Header.Set(handlers.TokenHeader, handlers.TokenPrefix+"session.secret")
+
+		w := httptest.NewRecorder()
+		mw(next).ServeHTTP(w, r.WithContext(expired))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("%s, expired deadline: want 503, have: %d %s", name, w.Code, w.Body)
+		}
+
+		w = httptest.NewRecorder()
+		mw(next).ServeHTTP(w, r.WithContext(canceled))
+		if w.Body.Len() != 0 {
+			t.Fatalf("%s, canceled request: want empty body, have: %s", name, w.Body)
+		}
+
+		w = httptest.NewRecorder()
+		mw(next).ServeHTTP(w, r)
+		if name == "required" && w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s, unknown session: want 401, have: %d %s", name, w.Code, w.Body)
+		}
+		if name == "optional" && w.Body.String() != "served" {
+			t.Fatalf("%s, unknown session: want anonymous request, have: %d %s", name, w.Code, w.Body)
+		}
+	}
+}
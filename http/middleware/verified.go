@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"rwa/http/handlers"
+	"rwa/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+type VerifiedGuard struct {
+	vs *services.VerificationService
+	us *services.UserService
+}
+
+func NewVerifiedGuard(vs *services.VerificationService, us *services.UserService) *VerifiedGuard {
+	return &VerifiedGuard{
+		vs: vs,
+		us: us,
+	}
+}
+
+// GetWriteMiddleware lets through reads and writes of users allowed to write,
+// it goes after auth middleware
+func (vg *VerifiedGuard) GetWriteMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			uId, err := handlers.GetUserIdFromRequestCtx(r)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(err.Error()))
+				return
+			}
+
+			user, err := vg.us.GetUserById(r.Context(), uId)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(err.Error()))
+				return
+			}
+
+			if !vg.vs.CanWrite(*user) {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte("Email is not verified"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -6,7 +6,7 @@ import (
 )
 
 type Article struct {
-	Author         User      `json:"user"`
+	Author         Profile   `json:"user"`
 	Body           string    `json:"body"`
 	Title          string    `json:"title"`
 	Description    string    `json:"description"`
@@ -18,6 +18,11 @@ type Article struct {
 	UpdatedAt      time.Time `json:"updatedAt"`
 }
 
+type TagCount struct {
+	Tag           string `json:"tag"`
+	ArticlesCount int    `json:"articlesCount"`
+}
+
 type ArticleInfo struct {
 	Body        string   `json:"body"`
 	Title       string   `json:"title"`
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+type Comment struct {
+	ID          int64     `json:"id"`
+	ArticleSlug string    `json:"-"`
+	Author      Profile   `json:"author"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type CommentInfo struct {
+	Body string `json:"body"`
+}
+
+func (i *CommentInfo) Validate() error {
+	if i.Body == "" {
+		return errors.New("Body is empty")
+	}
+
+	return nil
+}
@@ -3,5 +3,15 @@ package models
 import "errors"
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound         = errors.New("not found")
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrInvalidCredentials is the same for unknown email and wrong password
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrTooManyAttempts    = errors.New("too many login attempts, try later")
+
+	// ErrAlreadyUsed is returned for second use of single use token
+	ErrAlreadyUsed = errors.New("already used")
+	// ErrInvalidToken is returned for unknown, expired or used one-time tokens
+	ErrInvalidToken = errors.New("invalid or expired token")
 )
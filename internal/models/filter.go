@@ -0,0 +1,81 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type TagMode string
+
+const (
+	// TagModeAny matches articles having at least one of tags
+	TagModeAny TagMode = "any"
+	// TagModeAll matches articles having every tag
+	TagModeAll TagMode = "all"
+)
+
+// ArticleFilter combines all article list conditions, zero values mean no restriction.
+// Time bounds are inclusive
+type ArticleFilter struct {
+	// Query is full-text search over title, description and body,
+	// found articles are sorted by relevance
+	Query       string
+	AuthorId    int64
+	FavoritedBy int64
+	Tags        []string
+	TagMode     TagMode
+	ExcludeTags []string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+}
+
+// Match checks all conditions except FavoritedBy, which is stored apart from article
+func (f ArticleFilter) Match(a Article) bool {
+	if f.AuthorId != 0 && a.Author.ID != f.AuthorId {
+		return false
+	}
+
+	if len(f.Tags) != 0 && !f.matchTags(a.TagList) {
+		return false
+	}
+
+	for _, t := range f.ExcludeTags {
+		if slices.Contains(a.TagList, t) {
+			return false
+		}
+	}
+
+	return inRange(a.CreatedAt, f.CreatedFrom, f.CreatedTo) &&
+		inRange(a.UpdatedAt, f.UpdatedFrom, f.UpdatedTo)
+}
+
+func (f ArticleFilter) matchTags(tagList []string) bool {
+	if f.TagMode == TagModeAll {
+		for _, t := range f.Tags {
+			if !slices.Contains(tagList, t) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, t := range f.Tags {
+		if slices.Contains(tagList, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && t.After(to) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+type LoginAttempts struct {
+	Failures       int
+	FirstFailureAt time.Time
+	LockedUntil    time.Time
+}
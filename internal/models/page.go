@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ArticleCursor points to article position in lists sorted from newest to oldest
+type ArticleCursor struct {
+	CreatedAt time.Time
+	Slug      string
+}
+
+// IsAfter reports whether article goes after cursor position, i.e. it is older
+func (c ArticleCursor) IsAfter(a Article) bool {
+	if !a.CreatedAt.Equal(c.CreatedAt) {
+		return a.CreatedAt.Before(c.CreatedAt)
+	}
+
+	return a.Slug < c.Slug
+}
+
+type Page struct {
+	Limit  int
+	Offset int
+	// After switches to keyset pagination: Offset is ignored
+	// and only articles going after cursor are returned
+	After *ArticleCursor
+}
+
+func (a Article) Cursor() ArticleCursor {
+	return ArticleCursor{
+		CreatedAt: a.CreatedAt,
+		Slug:      a.Slug,
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+type PasswordChangeInfo struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+func (info *PasswordChangeInfo) Validate() error {
+	if info.CurrentPassword == "" {
+		return ValidationErrors{"currentPassword": {"can't be empty"}}
+	}
+
+	return nil
+}
@@ -2,10 +2,41 @@ package models
 
 import "time"
 
+// Session keeps only SHA-256 digest of token, raw token is known to client only.
+// ID is public and used to manage sessions
 type Session struct {
-	UserId    int64
+	ID         string    `json:"id"`
+	UserId     int64     `json:"-"`
+	FamilyId   string    `json:"-"`
+	TokenHash  []byte    `json:"-"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+}
+
+// RefreshToken is single use, after rotation it is kept as used to detect reuse.
+// All sessions and refresh tokens rotated from one login share FamilyId
+type RefreshToken struct {
+	ID        string
+	FamilyId  string
 	SessionId string
+	UserId    int64
+	TokenHash []byte
+	Used      bool
 	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// ClientInfo describes device session was started from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
 }
 
 func (s Session) GetUserId() int64 {
@@ -13,5 +44,5 @@ func (s Session) GetUserId() int64 {
 }
 
 func (s Session) GetSessionId() string {
-	return s.SessionId
+	return s.ID
 }
@@ -1,9 +1,6 @@
 package models
 
-import (
-	"errors"
-	"time"
-)
+import "time"
 
 type User struct {
 	ID             int64     `json:"-"`
@@ -13,9 +10,19 @@ type User struct {
 	Image          string    `json:"image"`
 	CreatedAt      time.Time `json:"createdAt"`
 	UpdatedAt      time.Time `json:"updatedAt"`
+	Verified       bool      `json:"verified"`
 	HashedPassword string    `json:"-"`
 }
 
+func (u User) ToProfile() Profile {
+	return Profile{
+		ID:       u.ID,
+		Username: u.Username,
+		Bio:      u.Bio,
+		Image:    u.Image,
+	}
+}
+
 type UserUpdateInfo struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
@@ -30,16 +37,3 @@ type UserCreateInfo struct {
 	Image    string `json:"image"`
 	Password string `json:"password"`
 }
-
-func (info *UserCreateInfo) Validate() error {
-	switch "" {
-	case info.Email:
-		return errors.New("Email is empty")
-	case info.Username:
-		return errors.New("Username is empty")
-	case info.Password:
-		return errors.New("Password is empty")
-	}
-
-	return nil
-}
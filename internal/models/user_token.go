@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type TokenPurpose string
+
+const (
+	PurposePasswordReset     TokenPurpose = "password_reset"
+	PurposeEmailVerification TokenPurpose = "email_verification"
+)
+
+// UserToken is single use token mailed to user, it is deleted once used
+type UserToken struct {
+	ID        string
+	UserId    int64
+	Purpose   TokenPurpose
+	TokenHash []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
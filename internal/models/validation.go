@@ -0,0 +1,44 @@
+package models
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ValidationErrors keeps messages by request field, like {"email": ["is invalid"]}
+type ValidationErrors map[string][]string
+
+func (e ValidationErrors) Add(field string, msg string) {
+	e[field] = append(e[field], msg)
+}
+
+// Err returns nil if there are no messages
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+func (e ValidationErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, field+" "+strings.Join(e[field], ", "))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// NormalizeIdentity folds username or email to the form they are unique in
+func NormalizeIdentity(s string) string {
+	return strings.ToLower(norm.NFKC.String(s))
+}
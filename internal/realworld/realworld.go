@@ -1,52 +1,378 @@
 package realworld
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"net/http"
+	"os"
+	"regexp"
+	"rwa/cmd/config"
 	"rwa/http/handlers"
 	"rwa/http/middleware"
+	"rwa/internal/repository/mysql"
 	"rwa/internal/repository/ram"
+	"rwa/internal/repository/sqlite"
 	"rwa/internal/services"
+	"rwa/pkg/clock"
+	"rwa/pkg/mailer"
+	"rwa/pkg/migrate"
 	"rwa/pkg/passwordcryptor"
+	"sync"
 
 	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
 )
 
-func GetApp() http.Handler {
+// App is api handler with its background workers, Close stops them
+type App struct {
+	http.Handler
+
+	cancel  context.CancelFunc
+	wg      *sync.WaitGroup
+	storage *storage
+}
+
+// GetApp makes app with config from environment, caller has to Close it
+func GetApp() *App {
+	return NewApp(config.InitConfig())
+}
+
+func NewApp(cfg *config.AppConfig) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	store, err := newStorage(cfg.Storage)
+	if err != nil {
+		panic(err)
+	}
 
 	router := mux.NewRouter()
-	createApi(router)
+	workers := createApi(router, cfg, store)
+
+	app := &App{
+		Handler: router,
+		cancel:  cancel,
+		wg:      &sync.WaitGroup{},
+		storage: store,
+	}
 
-	return router
+	for _, work := range workers {
+		app.wg.Add(1)
+		go func() {
+			defer app.wg.Done()
+			work(ctx)
+		}()
+	}
+
+	return app
+}
+
+// Close stops background workers, waits for them and closes storage
+func (a *App) Close() {
+	a.cancel()
+	a.wg.Wait()
+
+	if err := a.storage.close(); err != nil {
+		fmt.Println("close storage:", err)
+	}
 }
 
-func createApi(router *mux.Router) {
-	userRepo := ram.NewUserRepository()
-	sessionRepo := ram.NewSessionRepository()
-	articleRepo := ram.NewArticleRepository()
+type worker func(ctx context.Context)
+
+func createApi(router *mux.Router, cfg *config.AppConfig, store *storage) []worker {
+	userRepo := store.users
+	followRepo := store.follows
+	sessionRepo := store.sessions
+	favoriteRepo := store.favorites
+	articleRepo := store.articles
+	commentRepo := store.comments
+	loginAttemptRepo := ram.NewLoginAttemptRepository()
+	userTokenRepo := ram.NewUserTokenRepository()
+
+	hasher, err := newPasswordHasher(cfg.Password)
+	if err != nil {
+		panic(err)
+	}
 
-	userService := services.NewUserService(userRepo, passwordcryptor.PasswordCryptor{})
-	sessionService := services.NewSessionManager(sessionRepo, userService)
-	articleService := services.NewArticleService(articleRepo)
+	identityPolicy, err := newIdentityPolicy(cfg.Identity)
+	if err != nil {
+		panic(err)
+	}
 
-	userHandler := handlers.NewUserHandler(userService, sessionService)
+	userService := services.NewUserService(userRepo, followRepo, hasher, identityPolicy)
+	tokens, err := newTokenStrategy(cfg.Session.Token)
+	if err != nil {
+		panic(err)
+	}
+
+	sessionService := services.NewSessionManager(sessionRepo, userService, tokens, clock.Real{}, services.SessionPolicy{
+		TTL:         cfg.Session.TTL,
+		IdleTimeout: cfg.Session.IdleTimeout,
+		RefreshTTL:  cfg.Session.RefreshTTL,
+	})
+	authService := services.NewAuthService(userService, loginAttemptRepo, clock.Real{}, services.LoginPolicy{
+		MaxAccountFailures: cfg.Login.MaxAccountFailures,
+		MaxIPFailures:      cfg.Login.MaxIPFailures,
+		FailuresWindow:     cfg.Login.FailuresWindow,
+		LockDuration:       cfg.Login.LockDuration,
+	})
+	appMailer := newMailer(cfg.Mail)
+	passwordService := services.NewPasswordService(userService, sessionService, userTokenRepo, appMailer, clock.Real{}, services.PasswordResetPolicy{
+		TokenTTL: cfg.Password.ResetTokenTTL,
+	})
+	verificationService := services.NewVerificationService(userService, userTokenRepo, appMailer, clock.Real{}, services.VerificationPolicy{
+		TokenTTL:              cfg.Verification.TokenTTL,
+		ResendInterval:        cfg.Verification.ResendInterval,
+		AllowUnverifiedWrites: !cfg.Verification.UnverifiedReadOnly,
+	})
+	articleService := services.NewArticleService(articleRepo, favoriteRepo, commentRepo, userService)
+	commentService := services.NewCommentService(commentRepo, userService)
+
+	userHandler := handlers.NewUserHandler(userService, sessionService, authService, verificationService)
 	articleHandler := handlers.NewArticleHandler(articleService, userService)
+	commentHandler := handlers.NewCommentHandler(commentService, articleService, userService)
+	profileHandler := handlers.NewProfileHandler(userService)
+	tagHandler := handlers.NewTagHandler(articleService)
+	sessionHandler := handlers.NewSessionHandler(sessionService, userService)
+	passwordHandler := handlers.NewPasswordHandler(passwordService, userService)
+	verificationHandler := handlers.NewVerificationHandler(verificationService, userService)
 
-	sessionGuard := middleware.NewSessionGuard(sessionService)
+	router.Use(middleware.Timeout(cfg.HTTP.RequestTimeout))
+
+	sessionGuard := middleware.NewSessionGuard(sessionService, tokens)
 	authMiddleware := sessionGuard.GetAuthMiddleware()
+	optionalAuthMiddleware := sessionGuard.GetOptionalAuthMiddleware()
+	writeMiddleware := middleware.NewVerifiedGuard(verificationService, userService).GetWriteMiddleware()
 
 	router.HandleFunc("/api/users", userHandler.Register).Methods("POST")
 	router.HandleFunc("/api/users/login", userHandler.Login).Methods("POST")
+	router.HandleFunc("/api/users/token/refresh", userHandler.Refresh).Methods("POST")
+	router.HandleFunc("/api/users/password-reset", passwordHandler.RequestReset).Methods("POST")
+	router.HandleFunc("/api/users/password-reset/confirm", passwordHandler.ConfirmReset).Methods("POST")
+	router.HandleFunc("/api/users/verify", verificationHandler.Verify).Methods("POST")
 
 	ur := router.PathPrefix("/api/user").Subrouter()
 	ur.HandleFunc("", userHandler.Info).Methods("GET")
 	ur.HandleFunc("", userHandler.Update).Methods("PUT")
+	ur.HandleFunc("/password", passwordHandler.Change).Methods("PUT")
+	ur.HandleFunc("/verify/resend", verificationHandler.Resend).Methods("POST")
 	ur.HandleFunc("/logout", userHandler.Logout).Methods("POST")
+	ur.HandleFunc("/logout-all", sessionHandler.LogoutAll).Methods("POST")
+	ur.HandleFunc("/sessions", sessionHandler.List).Methods("GET")
+	ur.HandleFunc("/sessions/{id}", sessionHandler.Revoke).Methods("DELETE")
 	ur.Use(authMiddleware)
 
-	router.HandleFunc("/api/articles", articleHandler.Get).Methods("GET")
+	router.HandleFunc("/api/tags", tagHandler.Get).Methods("GET")
+
+	pp := router.PathPrefix("/api/profiles").Methods("GET").Subrouter()
+	pp.Use(optionalAuthMiddleware)
+	pp.HandleFunc("/{username}", profileHandler.Get)
+
+	fr := router.PathPrefix("/api/profiles").Subrouter()
+	fr.Use(authMiddleware, writeMiddleware)
+	fr.HandleFunc("/{username}/follow", profileHandler.Follow).Methods("POST")
+	fr.HandleFunc("/{username}/follow", profileHandler.Unfollow).Methods("DELETE")
+
+	// feed must be registered before /{slug} to not be shadowed
+	router.Handle("/api/articles/feed", authMiddleware(http.HandlerFunc(articleHandler.Feed))).Methods("GET")
+
+	pr := router.PathPrefix("/api/articles").Methods("GET").Subrouter()
+	pr.Use(optionalAuthMiddleware)
+	pr.HandleFunc("", articleHandler.Get)
+	pr.HandleFunc("/{slug}", articleHandler.GetBySlug)
+	pr.HandleFunc("/{slug}/comments", commentHandler.Get)
 
 	ar := router.PathPrefix("/api/articles").Subrouter()
-	ar.Use(authMiddleware)
+	ar.Use(authMiddleware, writeMiddleware)
 	ar.HandleFunc("", articleHandler.Create).Methods("POST")
+	ar.HandleFunc("/{slug}", articleHandler.Update).Methods("PUT")
+	ar.HandleFunc("/{slug}", articleHandler.Delete).Methods("DELETE")
+	ar.HandleFunc("/{slug}/favorite", articleHandler.Favorite).Methods("POST")
+	ar.HandleFunc("/{slug}/favorite", articleHandler.Unfavorite).Methods("DELETE")
+	ar.HandleFunc("/{slug}/comments", commentHandler.Create).Methods("POST")
+	ar.HandleFunc("/{slug}/comments/{id:[0-9]+}", commentHandler.Delete).Methods("DELETE")
+
+	return []worker{
+		func(ctx context.Context) {
+			services.RunJanitor(ctx, cfg.Session.JanitorInterval, map[string]services.Sweep{
+				"session":       sessionService.DeleteExpired,
+				"login attempt": authService.DeleteExpired,
+				"user token":    services.NewUserTokenSweep(userTokenRepo, clock.Real{}),
+			})
+		},
+	}
+}
+
+// storage keeps repositories which can be backed by database
+type storage struct {
+	users     services.UserRepository
+	sessions  services.SessionRepository
+	articles  services.ArticleRepository
+	favorites services.FavoriteRepository
+	comments  services.CommentRepository
+	follows   services.FollowRepository
+
+	close func() error
+}
+
+func newStorage(cfg config.StorageConfig) (*storage, error) {
+	if cfg.Backend == config.StorageRAM {
+		favoriteRepo := ram.NewFavoriteRepository()
+
+		return &storage{
+			users:     ram.NewUserRepository(),
+			sessions:  ram.NewSessionRepository(),
+			articles:  ram.NewArticleRepository(favoriteRepo),
+			favorites: favoriteRepo,
+			comments:  ram.NewCommentRepository(),
+			follows:   ram.NewFollowRepository(),
+			close:     func() error { return nil },
+		}, nil
+	}
+
+	db, migrator, err := OpenDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.AutoMigrate {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+	}
+
+	switch cfg.Backend {
+	case config.StorageSQLite:
+		articleRepo, err := sqlite.NewArticleRepository(db)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("load articles: %w", err)
+		}
+
+		return &storage{
+			users:     sqlite.NewUserRepository(db),
+			sessions:  sqlite.NewSessionRepository(db),
+			articles:  articleRepo,
+			favorites: sqlite.NewFavoriteRepository(db),
+			comments:  sqlite.NewCommentRepository(db),
+			follows:   sqlite.NewFollowRepository(db),
+			close:     db.Close,
+		}, nil
+	default:
+		return &storage{
+			users:     mysql.NewUserRepository(db),
+			sessions:  mysql.NewSessionRepository(db),
+			articles:  mysql.NewArticleRepository(db),
+			favorites: mysql.NewFavoriteRepository(db),
+			comments:  mysql.NewCommentRepository(db),
+			follows:   mysql.NewFollowRepository(db),
+			close:     db.Close,
+		}, nil
+	}
+}
+
+// OpenDatabase connects to SQL storage backend and prepares its migrations
+func OpenDatabase(cfg config.StorageConfig) (*sqlx.DB, *migrate.Migrator, error) {
+	var (
+		db          *sqlx.DB
+		newMigrator func(*sqlx.DB) (*migrate.Migrator, error)
+		err         error
+	)
+
+	switch cfg.Backend {
+	case config.StorageSQLite:
+		db, err = sqlite.Open(cfg.SQLitePath)
+		newMigrator = sqlite.NewMigrator
+	case config.StorageMySQL:
+		db, err = mysql.Open(cfg.MySQLDSN)
+		newMigrator = mysql.NewMigrator
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("open %s: %w", cfg.Backend, err)
+	}
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("load migrations: %w", err)
+	}
+
+	return db, migrator, nil
+}
+
+func newTokenStrategy(cfg config.TokenConfig) (services.TokenStrategy, error) {
+	switch cfg.Mode {
+	case config.TokenModeOpaque:
+		return services.OpaqueTokens{}, nil
+	case config.TokenModeJWT:
+		key, err := os.ReadFile(cfg.JWTKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read jwt key: %w", err)
+		}
+		return services.NewJWTTokensFromKey(cfg.JWTAlgorithm, bytes.TrimSpace(key))
+	default:
+		return nil, fmt.Errorf("unknown token mode %q", cfg.Mode)
+	}
+}
+
+func newMailer(cfg config.MailConfig) mailer.Mailer {
+	if cfg.SMTPAddr == "" {
+		return mailer.NewMemory()
+	}
+
+	return mailer.NewSMTP(cfg.SMTPAddr, cfg.From, cfg.SMTPUsername, cfg.SMTPPassword)
+}
+
+// newPasswordHasher hashes with configured algorithm and still accepts hashes of the others
+func newPasswordHasher(cfg config.PasswordConfig) (passwordcryptor.Hasher, error) {
+	hashers := map[string]passwordcryptor.Hasher{
+		config.PasswordAlgorithmBcrypt:   passwordcryptor.Bcrypt{Cost: cfg.BcryptCost},
+		config.PasswordAlgorithmArgon2id: passwordcryptor.NewArgon2id(),
+		config.PasswordAlgorithmScrypt:   passwordcryptor.NewScrypt(),
+	}
+
+	primary, ok := hashers[cfg.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown password algorithm %q", cfg.Algorithm)
+	}
+
+	legacy := make([]passwordcryptor.Hasher, 0, len(hashers)-1)
+	for alg, h := range hashers {
+		if alg != cfg.Algorithm {
+			legacy = append(legacy, h)
+		}
+	}
+
+	if cfg.Pepper != "" {
+		return passwordcryptor.NewPepperedChain([]byte(cfg.Pepper), primary, legacy...), nil
+	}
+
+	return passwordcryptor.NewChain(primary, legacy...), nil
+}
+
+func newIdentityPolicy(cfg config.IdentityConfig) (services.IdentityPolicy, error) {
+	usernamePattern, err := regexp.Compile(cfg.UsernamePattern)
+	if err != nil {
+		return services.IdentityPolicy{}, fmt.Errorf("username pattern: %w", err)
+	}
+
+	policy := services.IdentityPolicy{
+		MinPasswordLength: cfg.MinPasswordLength,
+		PasswordClasses:   cfg.PasswordClasses,
+		MinUsernameLength: cfg.MinUsernameLength,
+		MaxUsernameLength: cfg.MaxUsernameLength,
+		UsernamePattern:   usernamePattern,
+	}
+
+	if cfg.CommonPasswordsFile != "" {
+		policy.CommonPasswords, err = services.LoadCommonPasswords(cfg.CommonPasswordsFile)
+		if err != nil {
+			return services.IdentityPolicy{}, fmt.Errorf("common passwords: %w", err)
+		}
+	}
 
+	return policy, nil
 }
@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"context"
+	"rwa/internal/models"
+	"rwa/internal/repository/sqlrepo"
+	"rwa/pkg/fulltext"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ArticleRepository searches articles with FULLTEXT index, the rest is common for SQL databases
+type ArticleRepository struct {
+	*sqlrepo.ArticleRepository
+	db *sqlx.DB
+}
+
+func NewArticleRepository(db *sqlx.DB) *ArticleRepository {
+	return &ArticleRepository{
+		ArticleRepository: sqlrepo.NewArticleRepository(db, dialect),
+		db:                db,
+	}
+}
+
+// Find translates filter into single query, search is a condition of it
+func (r *ArticleRepository) Find(ctx context.Context, filter models.ArticleFilter, page models.Page) ([]*models.Article, int, error) {
+	q := r.Query(filter)
+
+	if search := booleanQuery(fulltext.ParseQuery(filter.Query)); search != "" {
+		q.Cond("MATCH (a.title, a.description, a.body) AGAINST (? IN BOOLEAN MODE)", search)
+
+		// title matches are the most valuable, as in ram index
+		q.Score = "3 * MATCH (a.title) AGAINST (? IN BOOLEAN MODE) + " +
+			"2 * MATCH (a.description) AGAINST (? IN BOOLEAN MODE) + " +
+			"MATCH (a.body) AGAINST (? IN BOOLEAN MODE)"
+		q.ScoreArgs = []interface{}{search, search, search}
+	}
+
+	return r.Select(ctx, r.db, q, page)
+}
+
+// booleanQuery makes every term required: words, word* prefixes and "quoted phrases".
+// Tokens contain only letters and digits, so they can't break boolean syntax
+func booleanQuery(query fulltext.Query) string {
+	terms := make([]string, 0, len(query))
+	for _, t := range query {
+		switch {
+		case len(t.Tokens) > 1:
+			terms = append(terms, `+"`+strings.Join(t.Tokens, " ")+`"`)
+		case t.Prefix:
+			terms = append(terms, "+"+t.Tokens[0]+"*")
+		default:
+			terms = append(terms, "+"+t.Tokens[0])
+		}
+	}
+
+	return strings.Join(terms, " ")
+}
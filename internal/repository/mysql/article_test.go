@@ -0,0 +1,238 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"rwa/internal/models"
+	"rwa/internal/repository/mysql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var articleColumns = []string{"id", "slug", "title", "description", "body", "created_at", "updated_at",
+	"author_id", "author_username", "author_bio", "author_image", "score"}
+
+func TestArticleRepositorySave(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewArticleRepository(db)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO articles`).
+		WithArgs("go", 1, "Go", "desc", "body", now, now).
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	// repeated tags are linked once
+	mock.ExpectExec(`INSERT IGNORE INTO tags \(name\) VALUES \(\?\), \(\?\)$`).
+		WithArgs("golang", "backend").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id, name FROM tags WHERE name IN \(\?, \?\)`).
+		WithArgs("golang", "backend").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "backend").AddRow(4, "golang"))
+	mock.ExpectExec(`INSERT INTO article_tags \(article_id, tag_id, position\) VALUES`).
+		WithArgs(10, 4, 0, 10, 3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := repo.Save(ctx, models.Article{
+		Author:      models.Profile{ID: 1},
+		Slug:        "go",
+		Title:       "Go",
+		Description: "desc",
+		Body:        "body",
+		TagList:     []string{"golang", "backend", "golang"},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestArticleRepositorySaveMixedCaseTags(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	article := models.Article{
+		Author:    models.Profile{ID: 1},
+		Slug:      "go",
+		TagList:   []string{"go", "Go"},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	expectTags := func(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO articles`).WillReturnResult(sqlmock.NewResult(10, 1))
+		mock.ExpectExec(`INSERT IGNORE INTO tags \(name\) VALUES \(\?\), \(\?\)$`).
+			WithArgs("go", "Go").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(`SELECT id, name FROM tags WHERE name IN \(\?, \?\)`).
+			WithArgs("go", "Go").
+			WillReturnRows(rows)
+	}
+
+	// binary collation keeps both names
+	db, mock := newMock(t)
+	expectTags(mock, sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "go").AddRow(4, "Go"))
+	mock.ExpectExec(`INSERT INTO article_tags \(article_id, tag_id, position\) VALUES`).
+		WithArgs(10, 3, 0, 10, 4, 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	if err := mysql.NewArticleRepository(db).Save(ctx, article); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// case insensitive collation merges them, missing id must not be linked as 0
+	db, mock = newMock(t)
+	expectTags(mock, sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "go"))
+	mock.ExpectRollback()
+
+	if err := mysql.NewArticleRepository(db).Save(ctx, article); err == nil {
+		t.Fatalf("want error for merged tag, have nil")
+	}
+}
+
+func TestArticleRepositoryGetBySlug(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewArticleRepository(db)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT .+ FROM articles a JOIN users u ON u.id = a.author_id WHERE a.slug = \?`).
+		WithArgs("go").
+		WillReturnRows(sqlmock.NewRows(articleColumns).
+			AddRow(10, "go", "Go", "desc", "body", now, now, 1, "gopher", "", "", 0))
+	mock.ExpectQuery(`SELECT at.article_id, t.name FROM article_tags at .+ ORDER BY at.article_id, at.position`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"article_id", "name"}).AddRow(10, "golang").AddRow(10, "backend"))
+
+	a, err := repo.GetBySlug(ctx, "go")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if a.Author.Username != "gopher" || !slices.Equal(a.TagList, []string{"golang", "backend"}) {
+		t.Fatalf("wrong article: %+v", a)
+	}
+}
+
+func TestArticleRepositoryFindByTags(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		name   string
+		filter models.ArticleFilter
+		from   string
+		args   []driver.Value
+	}{
+		{
+			name:   "any tag",
+			filter: models.ArticleFilter{Tags: []string{"a", "b"}},
+			from:   `JOIN (SELECT DISTINCT at.article_id FROM article_tags at JOIN tags t ON t.id = at.tag_id WHERE t.name IN (?, ?)) tagged ON tagged.article_id = a.id`,
+			args:   []driver.Value{"a", "b"},
+		},
+		{
+			name:   "all tags",
+			filter: models.ArticleFilter{Tags: []string{"a", "b"}, TagMode: models.TagModeAll},
+			from:   `JOIN (SELECT at.article_id FROM article_tags at JOIN tags t ON t.id = at.tag_id WHERE t.name IN (?, ?) GROUP BY at.article_id HAVING COUNT(*) = ?) tagged ON tagged.article_id = a.id`,
+			args:   []driver.Value{"a", "b", 2},
+		},
+		{
+			name:   "excluded tags",
+			filter: models.ArticleFilter{ExcludeTags: []string{"c"}, AuthorId: 5},
+			from:   `LEFT JOIN (SELECT DISTINCT at.article_id FROM article_tags at JOIN tags t ON t.id = at.tag_id WHERE t.name IN (?)) excluded ON excluded.article_id = a.id WHERE a.author_id = ? AND excluded.article_id IS NULL`,
+			args:   []driver.Value{"c", 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newMock(t)
+			repo := mysql.NewArticleRepository(db)
+
+			mock.ExpectQuery(`SELECT COUNT\(\*\) FROM articles a JOIN users u ON u.id = a.author_id ` + regexp.QuoteMeta(tc.from) + `$`).
+				WithArgs(tc.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(tc.from) + ` ORDER BY score DESC, a.created_at DESC, a.slug DESC LIMIT \? OFFSET \?`).
+				WithArgs(append(tc.args, 20, 0)...).
+				WillReturnRows(sqlmock.NewRows(articleColumns))
+
+			articles, total, err := repo.Find(ctx, tc.filter, models.Page{Limit: 20})
+			if err != nil || total != 0 || len(articles) != 0 {
+				t.Fatalf("want empty result, have: %v, %d, %v", articles, total, err)
+			}
+		})
+	}
+}
+
+func TestArticleRepositoryFindSearchAfterCursor(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewArticleRepository(db)
+
+	after := models.ArticleCursor{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Slug: "m"}
+	search := `+"hello world" +go* +rust`
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) .+ WHERE MATCH \(a.title, a.description, a.body\) AGAINST \(\? IN BOOLEAN MODE\)$`).
+		WithArgs(search).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`3 \* MATCH \(a.title\) .+ AS score .+ AND \(a.created_at < \? OR \(a.created_at = \? AND a.slug < \?\)\) ORDER BY`).
+		WithArgs(search, search, search, search, after.CreatedAt, after.CreatedAt, "m", 10, 0).
+		WillReturnRows(sqlmock.NewRows(articleColumns))
+
+	_, total, err := repo.Find(ctx, models.ArticleFilter{Query: `"Hello, world" go* Rust`}, models.Page{Limit: 10, Offset: 5, After: &after})
+	if err != nil || total != 2 {
+		t.Fatalf("want total 2, have: %d, %v", total, err)
+	}
+}
+
+func TestArticleRepositoryUpdate(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewArticleRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM articles WHERE slug = \? FOR UPDATE`).
+		WithArgs("old").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	if err := repo.Update(ctx, "old", models.Article{Slug: "new"}); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("missing article: want ErrNotFound, have: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM articles WHERE slug = \? FOR UPDATE`).
+		WithArgs("old").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectExec(`UPDATE articles SET slug = \?`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM article_tags WHERE article_id = \?`).
+		WithArgs(10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.Update(ctx, "old", models.Article{Slug: "new"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestArticleRepositoryTags(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewArticleRepository(db)
+
+	mock.ExpectQuery(`SELECT t.name, COUNT\(\*\) AS articles_count FROM tags t JOIN article_tags at`).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "articles_count"}).AddRow("golang", 2).AddRow("rust", 1))
+
+	tags, err := repo.Tags(ctx)
+	if err != nil || tags["golang"] != 2 || tags["rust"] != 1 {
+		t.Fatalf("wrong tags: %v, %v", tags, err)
+	}
+}
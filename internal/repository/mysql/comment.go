@@ -0,0 +1,11 @@
+package mysql
+
+import (
+	"rwa/internal/repository/sqlrepo"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func NewCommentRepository(db *sqlx.DB) *sqlrepo.CommentRepository {
+	return sqlrepo.NewCommentRepository(db, dialect)
+}
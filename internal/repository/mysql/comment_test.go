@@ -0,0 +1,60 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"rwa/internal/models"
+	"rwa/internal/repository/mysql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCommentRepositorySave(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewCommentRepository(db)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	comment := models.Comment{ArticleSlug: "go", Author: models.Profile{ID: 1}, Body: "nice", CreatedAt: now, UpdatedAt: now}
+
+	mock.ExpectExec(`INSERT INTO comments \(article_id, author_id, body, created_at, updated_at\) SELECT id, .+ FROM articles WHERE slug = \?`).
+		WithArgs(1, "nice", now, now, "go").
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	// nothing is inserted for missing article
+	mock.ExpectExec(`INSERT INTO comments`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	id, err := repo.Save(ctx, comment)
+	if err != nil || id != 7 {
+		t.Fatalf("want id 7, have: %d, %v", id, err)
+	}
+
+	if _, err := repo.Save(ctx, comment); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("missing article: want ErrNotFound, have: %v", err)
+	}
+}
+
+func TestCommentRepositoryGetAllByArticle(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewCommentRepository(db)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT .+ FROM comments c .+ WHERE a.slug = \? ORDER BY c.id`).
+		WithArgs("go").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "article_slug", "body", "created_at", "updated_at",
+			"author_id", "author_username", "author_bio", "author_image"}).
+			AddRow(1, "go", "first", now, now, 2, "gopher", "", "").
+			AddRow(2, "go", "second", now, now.Add(time.Minute), 3, "rustacean", "", ""))
+
+	comments, err := repo.GetAllByArticle(ctx, "go")
+	if err != nil || len(comments) != 2 {
+		t.Fatalf("want 2 comments, have: %v, %v", comments, err)
+	}
+
+	if c := comments[1]; c.ID != 2 || c.Author.Username != "rustacean" || !c.UpdatedAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("wrong comment: %+v", c)
+	}
+}
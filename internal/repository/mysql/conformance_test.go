@@ -0,0 +1,47 @@
+package mysql_test
+
+import (
+	"context"
+	"math"
+	"os"
+	"rwa/internal/repository/mysql"
+	"rwa/internal/repository/repotest"
+	"testing"
+)
+
+// TestConformance needs database of its own, tables in it are recreated for every check
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("RWA_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("RWA_TEST_MYSQL_DSN is not set")
+	}
+
+	db, err := mysql.Open(dsn)
+	if err != nil {
+		t.Fatalf("cannot open db: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := mysql.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("cannot load migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		if _, err := migrator.Down(ctx, math.MaxInt); err != nil {
+			t.Fatalf("cannot roll back migrations: %v", err)
+		}
+		if _, err := migrator.Up(ctx); err != nil {
+			t.Fatalf("cannot apply migrations: %v", err)
+		}
+
+		return repotest.Repositories{
+			Users:    mysql.NewUserRepository(db),
+			Sessions: mysql.NewSessionRepository(db),
+			Articles: mysql.NewArticleRepository(db),
+			Comments: mysql.NewCommentRepository(db),
+			Follows:  mysql.NewFollowRepository(db),
+		}
+	})
+}
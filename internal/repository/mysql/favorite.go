@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+)
+
+// FavoriteRepository references articles by id, so slug changes need no work
+type FavoriteRepository struct {
+	db *sqlx.DB
+}
+
+func NewFavoriteRepository(db *sqlx.DB) *FavoriteRepository {
+	return &FavoriteRepository{
+		db: db,
+	}
+}
+
+func (r *FavoriteRepository) Add(ctx context.Context, userId int64, slug string) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT IGNORE INTO favorites (user_id, article_id) SELECT ?, id FROM articles WHERE slug = ?",
+		userId, slug,
+	)
+
+	return err
+}
+
+func (r *FavoriteRepository) Remove(ctx context.Context, userId int64, slug string) error {
+	_, err := r.db.ExecContext(ctx,
+		"DELETE f FROM favorites f JOIN articles a ON a.id = f.article_id WHERE f.user_id = ? AND a.slug = ?",
+		userId, slug,
+	)
+
+	return err
+}
+
+func (r *FavoriteRepository) IsFavorited(ctx context.Context, userId int64, slug string) (bool, error) {
+	var favorited bool
+
+	err := r.db.GetContext(ctx, &favorited,
+		"SELECT EXISTS (SELECT 1 FROM favorites f JOIN articles a ON a.id = f.article_id WHERE f.user_id = ? AND a.slug = ?)",
+		userId, slug,
+	)
+
+	return favorited, err
+}
+
+func (r *FavoriteRepository) Count(ctx context.Context, slug string) (int, error) {
+	var count int
+
+	err := r.db.GetContext(ctx, &count,
+		"SELECT COUNT(*) FROM favorites f JOIN articles a ON a.id = f.article_id WHERE a.slug = ?",
+		slug,
+	)
+
+	return count, err
+}
+
+func (r *FavoriteRepository) UpdateSlug(ctx context.Context, oldSlug, newSlug string) error {
+	return nil
+}
+
+func (r *FavoriteRepository) DeleteAllByArticle(ctx context.Context, slug string) error {
+	_, err := r.db.ExecContext(ctx,
+		"DELETE f FROM favorites f JOIN articles a ON a.id = f.article_id WHERE a.slug = ?",
+		slug,
+	)
+
+	return err
+}
@@ -0,0 +1,11 @@
+package mysql
+
+import (
+	"rwa/internal/repository/sqlrepo"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func NewFollowRepository(db *sqlx.DB) *sqlrepo.FollowRepository {
+	return sqlrepo.NewFollowRepository(db, dialect)
+}
@@ -0,0 +1,32 @@
+package mysql_test
+
+import (
+	"context"
+	"rwa/internal/repository/mysql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestFollowRepositoryFollow(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewFollowRepository(db)
+
+	// repeated follow is no error
+	mock.ExpectExec(`INSERT IGNORE INTO follows \(follower_id, followee_id\) VALUES \(\?, \?\)`).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM follows WHERE follower_id = \? AND followee_id = \?\)`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	if err := repo.Follow(ctx, 1, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	following, err := repo.IsFollowing(ctx, 1, 2)
+	if err != nil || !following {
+		t.Fatalf("want following, have: %v, %v", following, err)
+	}
+}
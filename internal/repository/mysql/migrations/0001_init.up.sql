@@ -0,0 +1,93 @@
+CREATE TABLE users (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    email VARCHAR(255) NOT NULL,
+    email_key VARCHAR(255) NOT NULL,
+    username VARCHAR(64) NOT NULL,
+    username_key VARCHAR(64) NOT NULL,
+    bio TEXT NOT NULL,
+    image VARCHAR(1024) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME(6) NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY users_email_key (email_key),
+    UNIQUE KEY users_username_key (username_key)
+);
+
+CREATE TABLE sessions (
+    id VARCHAR(64) NOT NULL,
+    user_id BIGINT NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    token_hash VARBINARY(64) NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    last_seen_at DATETIME(6) NOT NULL,
+    user_agent VARCHAR(512) NOT NULL,
+    ip VARCHAR(64) NOT NULL,
+    PRIMARY KEY (id),
+    KEY sessions_user_id (user_id),
+    KEY sessions_family_id (family_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE refresh_tokens (
+    id VARCHAR(64) NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    session_id VARCHAR(64) NOT NULL,
+    user_id BIGINT NOT NULL,
+    token_hash VARBINARY(64) NOT NULL,
+    used BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME(6) NOT NULL,
+    expires_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (id),
+    KEY refresh_tokens_session_id (session_id),
+    KEY refresh_tokens_family_id (family_id),
+    KEY refresh_tokens_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE articles (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    slug VARCHAR(255) NOT NULL,
+    author_id BIGINT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    body MEDIUMTEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY articles_slug (slug),
+    KEY articles_author_created (author_id, created_at, slug),
+    KEY articles_created (created_at, slug),
+    FULLTEXT KEY articles_search (title, description, body),
+    FULLTEXT KEY articles_search_title (title),
+    FULLTEXT KEY articles_search_description (description),
+    FULLTEXT KEY articles_search_body (body),
+    FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE tags (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY tags_name (name)
+);
+
+CREATE TABLE article_tags (
+    article_id BIGINT NOT NULL,
+    tag_id BIGINT NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (article_id, tag_id),
+    KEY article_tags_tag_id (tag_id),
+    FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+
+CREATE TABLE favorites (
+    user_id BIGINT NOT NULL,
+    article_id BIGINT NOT NULL,
+    PRIMARY KEY (user_id, article_id),
+    KEY favorites_article_id (article_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE
+);
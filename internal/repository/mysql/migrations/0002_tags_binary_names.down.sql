@@ -0,0 +1,4 @@
+-- fails while tags differing only in case exist, they have to be merged first
+ALTER TABLE tags
+    DEFAULT CHARACTER SET utf8mb4,
+    MODIFY name VARCHAR(255) CHARACTER SET utf8mb4 NOT NULL;
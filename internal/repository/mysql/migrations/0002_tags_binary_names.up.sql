@@ -0,0 +1,4 @@
+-- tag names are compared byte by byte as in other backends, "go" and "Go" are two tags
+ALTER TABLE tags
+    DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin,
+    MODIFY name VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL;
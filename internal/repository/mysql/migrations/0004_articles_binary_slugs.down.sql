@@ -0,0 +1,3 @@
+-- fails while slugs differing only in case exist, they have to be renamed first
+ALTER TABLE articles
+    MODIFY slug VARCHAR(255) CHARACTER SET utf8mb4 NOT NULL;
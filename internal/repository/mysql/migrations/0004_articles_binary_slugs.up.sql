@@ -0,0 +1,3 @@
+-- slugs are compared byte by byte as in other backends, "go" and "Go" are two articles
+ALTER TABLE articles
+    MODIFY slug VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL;
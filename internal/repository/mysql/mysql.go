@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"rwa/internal/repository/sqlrepo"
+	"rwa/pkg/migrate"
+	"strings"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// errDuplicateEntry is returned by server on unique key violation
+	errDuplicateEntry = 1062
+
+	migrationsLock        = "rwa_schema_migrations"
+	migrationsLockTimeout = time.Minute
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+var dialect = sqlrepo.Dialect{
+	Time: func(t time.Time) interface{} {
+		return t
+	},
+	ScanTime: func(src interface{}) (time.Time, error) {
+		t, ok := src.(time.Time)
+		if !ok {
+			return time.Time{}, fmt.Errorf("cannot scan %T into time", src)
+		}
+
+		return t, nil
+	},
+	InsertIgnore: "INSERT IGNORE",
+	ForUpdate:    " FOR UPDATE",
+	DuplicateSlug: func(err error) bool {
+		return isDuplicate(err, "articles_slug")
+	},
+}
+
+// Open connects to database, times are read and written in UTC
+func Open(dsn string) (*sqlx.DB, error) {
+	cfg, err := driver.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+
+	return sqlx.Open("mysql", cfg.FormatDSN())
+}
+
+// NewMigrator manages schema of repositories of the package,
+// instances wait for each other on named server lock
+func NewMigrator(db *sqlx.DB) (*migrate.Migrator, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(db.DB, fsys, migrate.MySQLLock{
+		Name:    migrationsLock,
+		Timeout: migrationsLockTimeout,
+	})
+}
+
+// isDuplicate reports unique key violation, key narrows it to one index
+func isDuplicate(err error, key string) bool {
+	var mysqlErr *driver.MySQLError
+
+	return errors.As(err, &mysqlErr) &&
+		mysqlErr.Number == errDuplicateEntry &&
+		strings.Contains(mysqlErr.Message, key)
+}
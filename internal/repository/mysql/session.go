@@ -0,0 +1,280 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"rwa/internal/models"
+	"rwa/internal/repository/sqlrepo"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	sessionColumns      = "id, user_id, family_id, token_hash, created_at, last_seen_at, user_agent, ip"
+	refreshTokenColumns = "id, family_id, session_id, user_id, token_hash, used, created_at, expires_at"
+)
+
+type sessionRow struct {
+	ID         string    `db:"id"`
+	UserId     int64     `db:"user_id"`
+	FamilyId   string    `db:"family_id"`
+	TokenHash  []byte    `db:"token_hash"`
+	CreatedAt  time.Time `db:"created_at"`
+	LastSeenAt time.Time `db:"last_seen_at"`
+	UserAgent  string    `db:"user_agent"`
+	IP         string    `db:"ip"`
+}
+
+func (r sessionRow) toModel() *models.Session {
+	return &models.Session{
+		ID:         r.ID,
+		UserId:     r.UserId,
+		FamilyId:   r.FamilyId,
+		TokenHash:  r.TokenHash,
+		CreatedAt:  r.CreatedAt,
+		LastSeenAt: r.LastSeenAt,
+		UserAgent:  r.UserAgent,
+		IP:         r.IP,
+	}
+}
+
+type refreshTokenRow struct {
+	ID        string    `db:"id"`
+	FamilyId  string    `db:"family_id"`
+	SessionId string    `db:"session_id"`
+	UserId    int64     `db:"user_id"`
+	TokenHash []byte    `db:"token_hash"`
+	Used      bool      `db:"used"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+func (r refreshTokenRow) toModel() *models.RefreshToken {
+	return &models.RefreshToken{
+		ID:        r.ID,
+		FamilyId:  r.FamilyId,
+		SessionId: r.SessionId,
+		UserId:    r.UserId,
+		TokenHash: r.TokenHash,
+		Used:      r.Used,
+		CreatedAt: r.CreatedAt,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+type SessionRepository struct {
+	db *sqlx.DB
+}
+
+func NewSessionRepository(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
+
+func (r *SessionRepository) Get(ctx context.Context, sessionId string) (*models.Session, error) {
+	row := sessionRow{}
+
+	err := r.db.GetContext(ctx, &row, "SELECT "+sessionColumns+" FROM sessions WHERE id = ?", sessionId)
+	if err != nil {
+		return nil, sqlrepo.NotFound(err)
+	}
+
+	return row.toModel(), nil
+}
+
+func (r *SessionRepository) GetAllByUser(ctx context.Context, userId int64) ([]*models.Session, error) {
+	rows := []sessionRow{}
+
+	err := r.db.SelectContext(ctx, &rows, "SELECT "+sessionColumns+" FROM sessions WHERE user_id = ?", userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return []*models.Session{}, models.ErrNotFound
+	}
+
+	sessions := make([]*models.Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, row.toModel())
+	}
+
+	return sessions, nil
+}
+
+func (r *SessionRepository) Save(ctx context.Context, session models.Session) error {
+	return saveSession(ctx, r.db, session)
+}
+
+func (r *SessionRepository) UpdateLastSeen(ctx context.Context, sessionId string, lastSeenAt time.Time) error {
+	if _, err := r.Get(ctx, sessionId); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, "UPDATE sessions SET last_seen_at = ? WHERE id = ?", lastSeenAt, sessionId)
+
+	return err
+}
+
+func (r *SessionRepository) DeleteAllByUser(ctx context.Context, userId int64) error {
+	return sqlrepo.WithTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userId); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE user_id = ?", userId)
+
+		return err
+	})
+}
+
+// Delete removes session with its unused refresh token,
+// used ones are kept until expiration to detect reuse
+func (r *SessionRepository) Delete(ctx context.Context, sessionId string) error {
+	return sqlrepo.WithTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE session_id = ? AND used = FALSE", sessionId)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", sessionId)
+		if err != nil {
+			return err
+		}
+
+		return sqlrepo.CheckAffected(res)
+	})
+}
+
+func (r *SessionRepository) DeleteExpired(ctx context.Context, createdBefore, lastSeenBefore time.Time) (int, error) {
+	// refresh tokens outlive sessions, they are removed by DeleteExpiredRefreshTokens
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM sessions WHERE created_at < ? OR last_seen_at < ?",
+		createdBefore, lastSeenBefore,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := res.RowsAffected()
+
+	return int(deleted), err
+}
+
+func (r *SessionRepository) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	return saveRefreshToken(ctx, r.db, token)
+}
+
+func (r *SessionRepository) GetRefreshToken(ctx context.Context, id string) (*models.RefreshToken, error) {
+	row := refreshTokenRow{}
+
+	err := r.db.GetContext(ctx, &row, "SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE id = ?", id)
+	if err != nil {
+		return nil, sqlrepo.NotFound(err)
+	}
+
+	return row.toModel(), nil
+}
+
+// UseRefreshToken marks token as used, only one of concurrent callers succeeds
+func (r *SessionRepository) UseRefreshToken(ctx context.Context, id string) error {
+	return useRefreshToken(ctx, r.db, id)
+}
+
+// RotateRefreshToken uses token, replaces its session and saves new refresh token in one transaction
+func (r *SessionRepository) RotateRefreshToken(ctx context.Context, usedId string, session models.Session, token models.RefreshToken) error {
+	return sqlrepo.WithTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		if err := useRefreshToken(ctx, tx, usedId); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE id = (SELECT session_id FROM refresh_tokens WHERE id = ?)", usedId)
+		if err != nil {
+			return err
+		}
+
+		if err := saveSession(ctx, tx, session); err != nil {
+			return err
+		}
+
+		return saveRefreshToken(ctx, tx, token)
+	})
+}
+
+func (r *SessionRepository) DeleteFamily(ctx context.Context, familyId string) error {
+	return sqlrepo.WithTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE family_id = ?", familyId); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE family_id = ?", familyId)
+
+		return err
+	})
+}
+
+func (r *SessionRepository) DeleteFamiliesExcept(ctx context.Context, userId int64, familyId string) error {
+	return sqlrepo.WithTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ? AND family_id <> ?", userId, familyId)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE user_id = ? AND family_id <> ?", userId, familyId)
+
+		return err
+	})
+}
+
+func (r *SessionRepository) DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE expires_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := res.RowsAffected()
+
+	return int(deleted), err
+}
+
+func saveSession(ctx context.Context, db sqlx.ExecerContext, session models.Session) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO sessions ("+sessionColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		session.ID, session.UserId, session.FamilyId, session.TokenHash,
+		session.CreatedAt, session.LastSeenAt, session.UserAgent, session.IP,
+	)
+
+	return err
+}
+
+func saveRefreshToken(ctx context.Context, db sqlx.ExecerContext, token models.RefreshToken) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO refresh_tokens ("+refreshTokenColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		token.ID, token.FamilyId, token.SessionId, token.UserId, token.TokenHash,
+		token.Used, token.CreatedAt, token.ExpiresAt,
+	)
+
+	return err
+}
+
+func useRefreshToken(ctx context.Context, db sqlx.ExtContext, id string) error {
+	res, err := db.ExecContext(ctx, "UPDATE refresh_tokens SET used = TRUE WHERE id = ? AND used = FALSE", id)
+	if err != nil {
+		return err
+	}
+
+	err = sqlrepo.CheckAffected(res)
+	if !errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+
+	// nothing updated: token is either missing or already used
+	row := refreshTokenRow{}
+	if err := sqlx.GetContext(ctx, db, &row, "SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE id = ?", id); err != nil {
+		return sqlrepo.NotFound(err)
+	}
+
+	return models.ErrAlreadyUsed
+}
@@ -0,0 +1,152 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"rwa/internal/models"
+	"rwa/internal/repository/mysql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSessionRepositoryGetAllByUserEmpty(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewSessionRepository(db)
+
+	mock.ExpectQuery(`SELECT .+ FROM sessions WHERE user_id = \?`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	sessions, err := repo.GetAllByUser(ctx, 1)
+	if !errors.Is(err, models.ErrNotFound) || len(sessions) != 0 {
+		t.Fatalf("want empty list and ErrNotFound, have: %v, %v", sessions, err)
+	}
+}
+
+func TestSessionRepositoryGet(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewSessionRepository(db)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT .+ FROM sessions WHERE id = \?`).
+		WithArgs("s1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "token_hash", "created_at", "last_seen_at", "user_agent", "ip"}).
+			AddRow("s1", 2, "f1", []byte{1, 2}, now, now.Add(time.Minute), "curl", "127.0.0.1"))
+
+	s, err := repo.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.UserId != 2 || s.FamilyId != "f1" || string(s.TokenHash) != "\x01\x02" || !s.LastSeenAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("wrong session: %+v", s)
+	}
+}
+
+func TestSessionRepositoryUseRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewSessionRepository(db)
+
+	mock.ExpectExec(`UPDATE refresh_tokens SET used = TRUE WHERE id = \? AND used = FALSE`).
+		WithArgs("r1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.UseRefreshToken(ctx, "r1"); err != nil {
+		t.Fatalf("first use: unexpected error: %v", err)
+	}
+
+	// second use changes nothing, token is found already used
+	mock.ExpectExec(`UPDATE refresh_tokens SET used = TRUE`).
+		WithArgs("r1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT .+ FROM refresh_tokens WHERE id = \?`).
+		WithArgs("r1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "used"}).AddRow("r1", true))
+
+	if err := repo.UseRefreshToken(ctx, "r1"); !errors.Is(err, models.ErrAlreadyUsed) {
+		t.Fatalf("second use: want ErrAlreadyUsed, have: %v", err)
+	}
+
+	mock.ExpectExec(`UPDATE refresh_tokens SET used = TRUE`).
+		WithArgs("r2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT .+ FROM refresh_tokens WHERE id = \?`).
+		WithArgs("r2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if err := repo.UseRefreshToken(ctx, "r2"); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("missing token: want ErrNotFound, have: %v", err)
+	}
+}
+
+func TestSessionRepositoryDeleteKeepsUsedRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewSessionRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM refresh_tokens WHERE session_id = \? AND used = FALSE`).
+		WithArgs("s1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM sessions WHERE id = \?`).
+		WithArgs("s1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.Delete(ctx, "s1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM refresh_tokens`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM sessions`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	if err := repo.Delete(ctx, "s2"); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("missing session: want ErrNotFound, have: %v", err)
+	}
+}
+
+func TestSessionRepositoryDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewSessionRepository(db)
+
+	createdBefore := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastSeenBefore := createdBefore.Add(time.Hour)
+
+	// refresh tokens are not touched, they stay usable until their own expiration
+	mock.ExpectExec(`DELETE FROM sessions WHERE created_at < \? OR last_seen_at < \?`).
+		WithArgs(createdBefore, lastSeenBefore).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	deleted, err := repo.DeleteExpired(ctx, createdBefore, lastSeenBefore)
+	if err != nil || deleted != 3 {
+		t.Fatalf("want 3 deleted sessions, have: %d, %v", deleted, err)
+	}
+}
+
+func TestSessionRepositoryDeleteFamiliesExcept(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewSessionRepository(db)
+
+	// refresh tokens are deleted by user, their sessions may be swept already
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM sessions WHERE user_id = \? AND family_id <> \?`).
+		WithArgs(1, "f1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM refresh_tokens WHERE user_id = \? AND family_id <> \?`).
+		WithArgs(1, "f1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	if err := repo.DeleteFamiliesExcept(ctx, 1, "f1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
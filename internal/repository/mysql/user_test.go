@@ -0,0 +1,167 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"rwa/internal/models"
+	"rwa/internal/repository/mysql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+func newMock(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cannot create sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+		db.Close()
+	})
+
+	return sqlx.NewDb(db, "mysql"), mock
+}
+
+var userColumns = []string{"id", "email", "username", "bio", "image", "password_hash", "verified", "created_at", "updated_at"}
+
+func TestUserRepositoryGetByUsernameNormalized(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewUserRepository(db)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT .+ FROM users WHERE username_key = \?`).
+		WithArgs("golang").
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(7, "golang@example.com", "GoLang", "bio", "", "hash", true, created, created))
+
+	user, err := repo.GetByUsername(ctx, "GOLANG")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if user.ID != 7 || user.Username != "GoLang" || user.HashedPassword != "hash" || !user.Verified {
+		t.Fatalf("wrong user: %+v", user)
+	}
+}
+
+func TestUserRepositoryGetByIdNotFound(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewUserRepository(db)
+
+	mock.ExpectQuery(`SELECT .+ FROM users WHERE id = \?`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(userColumns))
+
+	if _, err := repo.GetById(ctx, 1); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("want ErrNotFound, have: %v", err)
+	}
+}
+
+func TestUserRepositorySave(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewUserRepository(db)
+
+	mock.ExpectQuery(`SELECT id, email_key, username_key FROM users`).
+		WithArgs("golang", "golang@example.com", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email_key", "username_key"}))
+	mock.ExpectExec(`INSERT INTO users`).
+		WithArgs("Golang@Example.com", "golang@example.com", "GoLang", "golang",
+			"", "", "hash", false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+
+	id, err := repo.Save(ctx, models.User{Email: "Golang@Example.com", Username: "GoLang", HashedPassword: "hash"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != 5 {
+		t.Fatalf("want id 5, have %d", id)
+	}
+}
+
+func TestUserRepositorySaveTaken(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewUserRepository(db)
+
+	mock.ExpectQuery(`SELECT id, email_key, username_key FROM users`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email_key", "username_key"}).
+			AddRow(1, "other@example.com", "golang").
+			AddRow(2, "golang@example.com", "other"))
+
+	_, err := repo.Save(ctx, models.User{Email: "golang@example.com", Username: "golang"})
+
+	var errs models.ValidationErrors
+	if !errors.As(err, &errs) || len(errs["username"]) != 1 || len(errs["email"]) != 1 {
+		t.Fatalf("want both fields taken, have: %v", err)
+	}
+}
+
+func TestUserRepositorySaveDuplicateRace(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewUserRepository(db)
+
+	// user appeared between check and insert
+	mock.ExpectQuery(`SELECT id, email_key, username_key FROM users`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email_key", "username_key"}))
+	mock.ExpectExec(`INSERT INTO users`).
+		WillReturnError(&driver.MySQLError{
+			Number:  1062,
+			Message: "Duplicate entry 'golang@example.com' for key 'users.users_email_key'",
+		})
+
+	_, err := repo.Save(ctx, models.User{Email: "golang@example.com", Username: "golang"})
+
+	var errs models.ValidationErrors
+	if !errors.As(err, &errs) || len(errs["email"]) != 1 {
+		t.Fatalf("want email taken, have: %v", err)
+	}
+}
+
+func TestUserRepositoryDeleteNotFound(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewUserRepository(db)
+
+	mock.ExpectExec(`DELETE FROM users WHERE id = \?`).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.Delete(ctx, models.User{ID: 3}); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("want ErrNotFound, have: %v", err)
+	}
+}
+
+func TestUserRepositoryReplacePasswordHash(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newMock(t)
+	repo := mysql.NewUserRepository(db)
+
+	mock.ExpectExec(`UPDATE users SET password_hash = \? WHERE id = \? AND password_hash = \?`).
+		WithArgs("new", 1, "old").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.ReplacePasswordHash(ctx, 1, "old", "new"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// hash was changed by someone else
+	mock.ExpectExec(`UPDATE users SET password_hash = \?`).
+		WithArgs("new", 1, "old").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.ReplacePasswordHash(ctx, 1, "old", "new"); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("changed hash: want ErrNotFound, have: %v", err)
+	}
+}
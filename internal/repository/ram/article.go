@@ -1,8 +1,13 @@
 package ram
 
 import (
+	"container/heap"
+	"context"
 	"errors"
 	"rwa/internal/models"
+	"rwa/pkg/fulltext"
+	"slices"
+	"sort"
 	"sync"
 )
 
@@ -10,49 +15,96 @@ type ArticleRepository struct {
 	store         map[string]models.Article
 	usersArticles map[int64][]string
 	tags          map[string][]string
+	searchIndex   *fulltext.Index
+	favoriteRepo  *FavoriteRepository
 
-	mu *sync.Mutex
+	mu *sync.RWMutex
 }
 
-func NewArticleRepository() *ArticleRepository {
+// favorites are kept in their own repository, article repository reads them
+// to filter articles favorited by user in the same query
+func NewArticleRepository(favoriteRepo *FavoriteRepository) *ArticleRepository {
 	return &ArticleRepository{
 		store:         make(map[string]models.Article),
 		usersArticles: make(map[int64][]string),
 		tags:          make(map[string][]string),
-		mu:            &sync.Mutex{},
+		searchIndex:   fulltext.NewIndex(),
+		favoriteRepo:  favoriteRepo,
+		mu:            &sync.RWMutex{},
 	}
 }
 
-func (r *ArticleRepository) GetAll(tags []string) ([]*models.Article, error) {
-	if len(tags) != 0 {
-		return r.getAllByTags(tags), nil
-	} else {
-		return r.getAll(), nil
+// Find answers any filter combination: candidates are taken from the narrowest index
+// and then checked against every condition
+func (r *ArticleRepository) Find(ctx context.Context, filter models.ArticleFilter, page models.Page) ([]*models.Article, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var favorites map[string]bool
+	if filter.FavoritedBy != 0 {
+		slugs, err := r.favoriteRepo.GetSlugsByUser(ctx, filter.FavoritedBy)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		favorites = make(map[string]bool, len(slugs))
+		for _, s := range slugs {
+			favorites[s] = true
+		}
 	}
-}
 
-func (r *ArticleRepository) getAllByTags(tags []string) []*models.Article {
-	slugs := r.getSlugsMapByTags(tags)
+	var scores map[string]float64
+	if query := fulltext.ParseQuery(filter.Query); len(query) != 0 {
+		scores = r.searchIndex.Search(query)
+	}
 
-	articles := make([]*models.Article, 0, len(slugs))
-	for s := range slugs {
-		articles = append(articles, r.getBySlug(s))
+	candidates := r.getCandidates(filter, favorites, scores)
+
+	slugs := make([]string, 0, len(candidates))
+	for _, s := range candidates {
+		if !r.isStored(s) || favorites != nil && !favorites[s] {
+			continue
+		}
+		if _, found := scores[s]; scores != nil && !found {
+			continue
+		}
+		if filter.Match(r.store[s]) {
+			slugs = append(slugs, s)
+		}
 	}
 
-	return articles
-}
+	articles, total := r.getPage(slugs, page, scores)
 
-func (r *ArticleRepository) getAll() []*models.Article {
-	articles := make([]*models.Article, 0, len(r.store))
+	return articles, total, nil
+}
 
-	for _, a := range r.store {
-		articles = append(articles, &a)
+func (r *ArticleRepository) getCandidates(filter models.ArticleFilter, favorites map[string]bool, scores map[string]float64) []string {
+	switch {
+	case scores != nil:
+		return mapKeys(scores)
+	case filter.AuthorId != 0:
+		return r.usersArticles[filter.AuthorId]
+	case len(filter.Tags) != 0 && filter.TagMode == models.TagModeAll:
+		rarest := filter.Tags[0]
+		for _, t := range filter.Tags[1:] {
+			if len(r.tags[t]) < len(r.tags[rarest]) {
+				rarest = t
+			}
+		}
+		return r.tags[rarest]
+	case len(filter.Tags) != 0:
+		return mapKeys(r.getSlugsMapByTags(filter.Tags))
+	case favorites != nil:
+		return mapKeys(favorites)
+	default:
+		return mapKeys(r.store)
 	}
-
-	return articles
 }
 
-func (r *ArticleRepository) GetBySlug(slug string) (*models.Article, error) {
+func (r *ArticleRepository) GetBySlug(ctx context.Context, slug string) (*models.Article, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	if !r.isStored(slug) {
 		return nil, models.ErrNotFound
 	}
@@ -60,65 +112,118 @@ func (r *ArticleRepository) GetBySlug(slug string) (*models.Article, error) {
 	return r.getBySlug(slug), nil
 }
 
-func (r *ArticleRepository) GetAllByUser(user models.User, tags []string) ([]*models.Article, error) {
-	slugs, exist := r.usersArticles[user.ID]
-	if !exist {
-		return []*models.Article{}, models.ErrNotFound
+// getPage sorts slugs from newest to oldest article and returns requested page with total count.
+// If relevance scores passed, the most relevant articles go first
+func (r *ArticleRepository) getPage(slugs []string, page models.Page, scores map[string]float64) ([]*models.Article, int) {
+	sorted := slices.Clone(slugs)
+	sort.Slice(sorted, func(i, j int) bool {
+		if scores != nil && scores[sorted[i]] != scores[sorted[j]] {
+			return scores[sorted[i]] > scores[sorted[j]]
+		}
+		return r.isOlder(sorted[j], sorted[i])
+	})
+
+	total := len(sorted)
+	offset := page.Offset
+	if page.After != nil {
+		offset = sort.Search(total, func(i int) bool {
+			return page.After.IsAfter(r.store[sorted[i]])
+		})
 	}
-
-	useTagsFilter := len(tags) != 0
-	var tagsSlugs map[string]bool
-	if useTagsFilter {
-		tagsSlugs = r.getSlugsMapByTags(tags)
+	if offset > total {
+		offset = total
 	}
+	end := min(offset+page.Limit, total)
 
-	articles := make([]*models.Article, 0, len(slugs))
-	for _, s := range slugs {
-		if useTagsFilter && !tagsSlugs[s] {
-			continue
-		}
+	articles := make([]*models.Article, 0, end-offset)
+	for _, s := range sorted[offset:end] {
 		articles = append(articles, r.getBySlug(s))
 	}
 
-	return articles, nil
+	return articles, total
 }
 
-func (r *ArticleRepository) Save(article models.Article) error {
-	r.mu.Lock()
-	r.mu.Unlock()
+// GetAllByUsers returns page of users articles sorted from newest to oldest and total count of them.
+// Users articles lists are kept sorted by creation time, so they are merged without touching other articles
+func (r *ArticleRepository) GetAllByUsers(ctx context.Context, userIds []int64, page models.Page) ([]*models.Article, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	h := &newestFirstHeap{repo: r}
+	total := 0
+	for _, id := range userIds {
+		slugs := r.usersArticles[id]
+		total += len(slugs)
+
+		// with keyset pagination each list is started right after cursor
+		pos := len(slugs) - 1
+		if page.After != nil {
+			pos = sort.Search(len(slugs), func(i int) bool {
+				return !page.After.IsAfter(r.store[slugs[i]])
+			}) - 1
+		}
 
-	slug := article.Slug
-	if r.isStored(slug) {
-		return errors.New("slug must be unique: " + slug)
+		if pos >= 0 {
+			h.cursors = append(h.cursors, &slugsCursor{slugs: slugs, pos: pos})
+		}
 	}
+	heap.Init(h)
 
-	r.store[slug] = article
-	r.saveSlugToUsersArticles(article.Author.ID, slug)
-	r.saveTagsAndSlug(article.TagList, article.Slug)
+	offset := page.Offset
+	if page.After != nil {
+		offset = 0
+	}
 
-	return nil
-}
+	articles := make([]*models.Article, 0, page.Limit)
+	for skipped := 0; h.Len() != 0 && len(articles) < page.Limit; {
+		c := h.cursors[0]
 
-func (r *ArticleRepository) Delete(article models.Article) error {
-	r.mu.Lock()
-	r.mu.Unlock()
+		if skipped < offset {
+			skipped++
+		} else {
+			articles = append(articles, r.getBySlug(c.current()))
+		}
 
-	slug := article.Slug
-	userId := article.Author.ID
+		c.pos--
+		if c.pos < 0 {
+			heap.Pop(h)
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
 
-	if !r.isStored(slug) {
-		return models.ErrNotFound
+	return articles, total, nil
+}
+
+func (r *ArticleRepository) Tags(ctx context.Context) (map[string]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := make(map[string]int, len(r.tags))
+	for t, slugs := range r.tags {
+		tags[t] = len(slugs)
 	}
 
-	delete(r.store, slug)
-	r.deleteSlugFromUsersArticles(userId, slug)
+	return tags, nil
+}
 
-	return nil
+func (r *ArticleRepository) Save(ctx context.Context, article models.Article) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.save(article)
+}
+
+func (r *ArticleRepository) Delete(ctx context.Context, article models.Article) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.delete(article.Slug)
 }
 
-func (r *ArticleRepository) Update(oldSlug string, article models.Article) error {
+func (r *ArticleRepository) Update(ctx context.Context, oldSlug string, article models.Article) error {
 	r.mu.Lock()
-	r.mu.Unlock()
+	defer r.mu.Unlock()
 
 	if !r.isStored(oldSlug) {
 		return models.ErrNotFound
@@ -129,14 +234,44 @@ func (r *ArticleRepository) Update(oldSlug string, article models.Article) error
 			return errors.New("slug must be unique: " + article.Slug)
 		}
 
-		r.Delete(*r.getBySlug(oldSlug))
-		r.deleteSlugFromUsersArticles(article.Author.ID, oldSlug)
+		r.delete(oldSlug)
 
-		r.Save(article)
-	} else {
-		r.store[oldSlug] = article
+		return r.save(article)
 	}
 
+	r.deleteTagsAndSlug(r.store[oldSlug].TagList, oldSlug)
+	r.store[oldSlug] = article
+	r.saveTagsAndSlug(article.TagList, oldSlug)
+	r.indexArticle(article)
+
+	return nil
+}
+
+func (r *ArticleRepository) save(article models.Article) error {
+	slug := article.Slug
+	if r.isStored(slug) {
+		return errors.New("slug must be unique: " + slug)
+	}
+
+	r.store[slug] = article
+	r.saveSlugToUsersArticles(article.Author.ID, slug)
+	r.saveTagsAndSlug(article.TagList, article.Slug)
+	r.indexArticle(article)
+
+	return nil
+}
+
+func (r *ArticleRepository) delete(slug string) error {
+	article, exist := r.store[slug]
+	if !exist {
+		return models.ErrNotFound
+	}
+
+	delete(r.store, slug)
+	r.deleteSlugFromUsersArticles(article.Author.ID, slug)
+	r.deleteTagsAndSlug(article.TagList, slug)
+	r.searchIndex.Remove(slug)
+
 	return nil
 }
 
@@ -151,13 +286,29 @@ func (r *ArticleRepository) getBySlug(slug string) *models.Article {
 	return &article
 }
 
+// saveSlugToUsersArticles keeps users articles sorted from oldest to newest,
+// slug must be already saved in store
 func (r *ArticleRepository) saveSlugToUsersArticles(userId int64, slug string) {
-	_, ex := r.usersArticles[userId]
-	if !ex {
-		r.usersArticles[userId] = make([]string, 0)
+	slugs := r.usersArticles[userId]
+	ind := sort.Search(len(slugs), func(i int) bool {
+		return !r.isOlder(slugs[i], slug)
+	})
+
+	slugs = append(slugs, "")
+	copy(slugs[ind+1:], slugs[ind:])
+	slugs[ind] = slug
+
+	r.usersArticles[userId] = slugs
+}
+
+// isOlder compares articles by creation time, slug breaks the tie
+func (r *ArticleRepository) isOlder(slugA, slugB string) bool {
+	a, b := r.store[slugA], r.store[slugB]
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
 	}
 
-	r.usersArticles[userId] = append(r.usersArticles[userId], slug)
+	return a.Slug < b.Slug
 }
 
 func (r *ArticleRepository) saveTagsAndSlug(tags []string, slug string) {
@@ -166,30 +317,37 @@ func (r *ArticleRepository) saveTagsAndSlug(tags []string, slug string) {
 			r.tags[t] = make([]string, 0)
 		}
 
+		if slices.Contains(r.tags[t], slug) {
+			continue
+		}
+
 		r.tags[t] = append(r.tags[t], slug)
 	}
 }
 
-func (r *ArticleRepository) deleteSlugFromUsersArticles(userId int64, slug string) {
-	deletingInd := -1
-	for i, s := range r.usersArticles[userId] {
-		if s == slug {
-			deletingInd = i
-			break
-		}
-	}
+func (r *ArticleRepository) deleteTagsAndSlug(tags []string, slug string) {
+	for _, t := range tags {
+		r.tags[t] = removeSlug(r.tags[t], slug)
 
-	if deletingInd != -1 {
-		if deletingInd == len(r.usersArticles[userId])-1 {
-			r.usersArticles[userId] = r.usersArticles[userId][:deletingInd]
-		} else if deletingInd == 0 {
-			r.usersArticles[userId] = r.usersArticles[userId][deletingInd:]
-		} else {
-			r.usersArticles[userId] = append(r.usersArticles[userId][:deletingInd], r.usersArticles[userId][deletingInd+1:]...)
+		if len(r.tags[t]) == 0 {
+			delete(r.tags, t)
 		}
 	}
 }
 
+// indexArticle replaces article in search index, title matches are the most valuable
+func (r *ArticleRepository) indexArticle(article models.Article) {
+	r.searchIndex.Add(article.Slug,
+		fulltext.Field{Text: article.Title, Weight: 3},
+		fulltext.Field{Text: article.Description, Weight: 2},
+		fulltext.Field{Text: article.Body, Weight: 1},
+	)
+}
+
+func (r *ArticleRepository) deleteSlugFromUsersArticles(userId int64, slug string) {
+	r.usersArticles[userId] = removeSlug(r.usersArticles[userId], slug)
+}
+
 func (r *ArticleRepository) getSlugsMapByTags(tags []string) map[string]bool {
 	slugs := make(map[string]bool)
 
@@ -201,3 +359,22 @@ func (r *ArticleRepository) getSlugsMapByTags(tags []string) map[string]bool {
 
 	return slugs
 }
+
+func removeSlug(slugs []string, slug string) []string {
+	for i, s := range slugs {
+		if s == slug {
+			return append(slugs[:i:i], slugs[i+1:]...)
+		}
+	}
+
+	return slugs
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
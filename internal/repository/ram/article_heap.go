@@ -0,0 +1,41 @@
+package ram
+
+type slugsCursor struct {
+	slugs []string
+	pos   int
+}
+
+func (c *slugsCursor) current() string {
+	return c.slugs[c.pos]
+}
+
+// newestFirstHeap merges several sorted slugs lists, newest article is on top
+type newestFirstHeap struct {
+	cursors []*slugsCursor
+	repo    *ArticleRepository
+}
+
+func (h *newestFirstHeap) Len() int {
+	return len(h.cursors)
+}
+
+func (h *newestFirstHeap) Less(i, j int) bool {
+	return h.repo.isOlder(h.cursors[j].current(), h.cursors[i].current())
+}
+
+func (h *newestFirstHeap) Swap(i, j int) {
+	h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i]
+}
+
+func (h *newestFirstHeap) Push(x interface{}) {
+	h.cursors = append(h.cursors, x.(*slugsCursor))
+}
+
+func (h *newestFirstHeap) Pop() interface{} {
+	old := h.cursors
+	n := len(old)
+	c := old[n-1]
+	h.cursors = old[:n-1]
+
+	return c
+}
@@ -0,0 +1,120 @@
+package ram
+
+import (
+	"context"
+	"rwa/internal/models"
+	"sync"
+	"sync/atomic"
+)
+
+type CommentRepository struct {
+	idCounter       atomic.Int64
+	store           map[int64]models.Comment
+	articleComments map[string][]int64
+
+	mu *sync.RWMutex
+}
+
+func NewCommentRepository() *CommentRepository {
+	return &CommentRepository{
+		idCounter:       atomic.Int64{},
+		store:           make(map[int64]models.Comment),
+		articleComments: make(map[string][]int64),
+		mu:              &sync.RWMutex{},
+	}
+}
+
+func (r *CommentRepository) GetById(ctx context.Context, id int64) (*models.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comment, exist := r.store[id]
+	if !exist {
+		return nil, models.ErrNotFound
+	}
+
+	return &comment, nil
+}
+
+func (r *CommentRepository) GetAllByArticle(ctx context.Context, slug string) ([]*models.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := r.articleComments[slug]
+
+	comments := make([]*models.Comment, 0, len(ids))
+	for _, id := range ids {
+		c := r.store[id]
+		comments = append(comments, &c)
+	}
+
+	return comments, nil
+}
+
+func (r *CommentRepository) Save(ctx context.Context, comment models.Comment) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment.ID = r.idCounter.Add(1)
+
+	r.store[comment.ID] = comment
+	r.articleComments[comment.ArticleSlug] = append(r.articleComments[comment.ArticleSlug], comment.ID)
+
+	return comment.ID, nil
+}
+
+func (r *CommentRepository) Delete(ctx context.Context, comment models.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exist := r.store[comment.ID]
+	if !exist {
+		return models.ErrNotFound
+	}
+
+	delete(r.store, comment.ID)
+
+	ids := r.articleComments[stored.ArticleSlug]
+	for i, id := range ids {
+		if id == comment.ID {
+			r.articleComments[stored.ArticleSlug] = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (r *CommentRepository) DeleteAllByArticle(ctx context.Context, slug string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range r.articleComments[slug] {
+		delete(r.store, id)
+	}
+
+	delete(r.articleComments, slug)
+
+	return nil
+}
+
+func (r *CommentRepository) UpdateSlug(ctx context.Context, oldSlug, newSlug string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids, exist := r.articleComments[oldSlug]
+	if !exist {
+		return nil
+	}
+
+	for _, id := range ids {
+		c := r.store[id]
+		c.ArticleSlug = newSlug
+		r.store[id] = c
+	}
+
+	delete(r.articleComments, oldSlug)
+	r.articleComments[newSlug] = ids
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package ram_test
+
+import (
+	"rwa/internal/repository/ram"
+	"rwa/internal/repository/repotest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		return repotest.Repositories{
+			Users:    ram.NewUserRepository(),
+			Sessions: ram.NewSessionRepository(),
+			Articles: ram.NewArticleRepository(ram.NewFavoriteRepository()),
+			Comments: ram.NewCommentRepository(),
+			Follows:  ram.NewFollowRepository(),
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package ram
+
+import (
+	"context"
+	"sync"
+)
+
+type FavoriteRepository struct {
+	articleFans   map[string]map[int64]bool
+	userFavorites map[int64]map[string]bool
+
+	mu *sync.RWMutex
+}
+
+func NewFavoriteRepository() *FavoriteRepository {
+	return &FavoriteRepository{
+		articleFans:   make(map[string]map[int64]bool),
+		userFavorites: make(map[int64]map[string]bool),
+		mu:            &sync.RWMutex{},
+	}
+}
+
+func (r *FavoriteRepository) Add(ctx context.Context, userId int64, slug string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ex := r.articleFans[slug]; !ex {
+		r.articleFans[slug] = make(map[int64]bool)
+	}
+	if _, ex := r.userFavorites[userId]; !ex {
+		r.userFavorites[userId] = make(map[string]bool)
+	}
+
+	r.articleFans[slug][userId] = true
+	r.userFavorites[userId][slug] = true
+
+	return nil
+}
+
+func (r *FavoriteRepository) Remove(ctx context.Context, userId int64, slug string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.articleFans[slug], userId)
+	delete(r.userFavorites[userId], slug)
+
+	return nil
+}
+
+func (r *FavoriteRepository) IsFavorited(ctx context.Context, userId int64, slug string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.articleFans[slug][userId], nil
+}
+
+func (r *FavoriteRepository) Count(ctx context.Context, slug string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.articleFans[slug]), nil
+}
+
+func (r *FavoriteRepository) GetSlugsByUser(ctx context.Context, userId int64) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	favorites := r.userFavorites[userId]
+	slugs := make([]string, 0, len(favorites))
+	for s := range favorites {
+		slugs = append(slugs, s)
+	}
+
+	return slugs, nil
+}
+
+func (r *FavoriteRepository) UpdateSlug(ctx context.Context, oldSlug, newSlug string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fans, exist := r.articleFans[oldSlug]
+	if !exist {
+		return nil
+	}
+
+	for uId := range fans {
+		delete(r.userFavorites[uId], oldSlug)
+		r.userFavorites[uId][newSlug] = true
+	}
+
+	delete(r.articleFans, oldSlug)
+	r.articleFans[newSlug] = fans
+
+	return nil
+}
+
+func (r *FavoriteRepository) DeleteAllByArticle(ctx context.Context, slug string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for uId := range r.articleFans[slug] {
+		delete(r.userFavorites[uId], slug)
+	}
+
+	delete(r.articleFans, slug)
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package ram
+
+import (
+	"context"
+	"sync"
+)
+
+type FollowRepository struct {
+	followees map[int64]map[int64]bool
+
+	mu *sync.RWMutex
+}
+
+func NewFollowRepository() *FollowRepository {
+	return &FollowRepository{
+		followees: make(map[int64]map[int64]bool),
+		mu:        &sync.RWMutex{},
+	}
+}
+
+func (r *FollowRepository) Follow(ctx context.Context, followerId, followeeId int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ex := r.followees[followerId]; !ex {
+		r.followees[followerId] = make(map[int64]bool)
+	}
+
+	r.followees[followerId][followeeId] = true
+
+	return nil
+}
+
+func (r *FollowRepository) Unfollow(ctx context.Context, followerId, followeeId int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.followees[followerId], followeeId)
+
+	return nil
+}
+
+func (r *FollowRepository) IsFollowing(ctx context.Context, followerId, followeeId int64) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.followees[followerId][followeeId], nil
+}
+
+func (r *FollowRepository) GetFollowees(ctx context.Context, followerId int64) ([]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	followees := make([]int64, 0, len(r.followees[followerId]))
+	for id := range r.followees[followerId] {
+		followees = append(followees, id)
+	}
+
+	return followees, nil
+}
@@ -0,0 +1,65 @@
+package ram
+
+import (
+	"context"
+	"rwa/internal/models"
+	"sync"
+	"time"
+)
+
+type LoginAttemptRepository struct {
+	store map[string]models.LoginAttempts
+	mu    *sync.RWMutex
+}
+
+func NewLoginAttemptRepository() *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		store: make(map[string]models.LoginAttempts),
+		mu:    &sync.RWMutex{},
+	}
+}
+
+func (r *LoginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	attempts, exist := r.store[key]
+	if !exist {
+		return nil, models.ErrNotFound
+	}
+
+	return &attempts, nil
+}
+
+func (r *LoginAttemptRepository) Save(ctx context.Context, key string, attempts models.LoginAttempts) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.store[key] = attempts
+
+	return nil
+}
+
+func (r *LoginAttemptRepository) Delete(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.store, key)
+
+	return nil
+}
+
+func (r *LoginAttemptRepository) DeleteExpired(ctx context.Context, lockedBefore, failedBefore time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for key, attempts := range r.store {
+		if attempts.LockedUntil.Before(lockedBefore) && attempts.FirstFailureAt.Before(failedBefore) {
+			delete(r.store, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -1,25 +1,34 @@
 package ram
 
 import (
+	"context"
 	"rwa/internal/models"
 	"sync"
+	"time"
 )
 
 type SessionRepository struct {
 	store           map[string]models.Session
 	userSessionsMap map[int64][]string
-	mu              *sync.Mutex
+	refreshTokens   map[string]models.RefreshToken
+	sessionRefresh  map[string]string
+	mu              *sync.RWMutex
 }
 
 func NewSessionRepository() *SessionRepository {
 	return &SessionRepository{
 		store:           make(map[string]models.Session),
 		userSessionsMap: make(map[int64][]string),
-		mu:              &sync.Mutex{},
+		refreshTokens:   make(map[string]models.RefreshToken),
+		sessionRefresh:  make(map[string]string),
+		mu:              &sync.RWMutex{},
 	}
 }
 
-func (r *SessionRepository) Get(sessionId string) (*models.Session, error) {
+func (r *SessionRepository) Get(ctx context.Context, sessionId string) (*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	s, exist := r.store[sessionId]
 	if !exist {
 		return nil, models.ErrNotFound
@@ -27,7 +36,10 @@ func (r *SessionRepository) Get(sessionId string) (*models.Session, error) {
 
 	return &s, nil
 }
-func (r *SessionRepository) GetAllByUser(userId int64) ([]*models.Session, error) {
+func (r *SessionRepository) GetAllByUser(ctx context.Context, userId int64) ([]*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	m, exist := r.userSessionsMap[userId]
 
 	if !exist || len(m) == 0 {
@@ -42,21 +54,29 @@ func (r *SessionRepository) GetAllByUser(userId int64) ([]*models.Session, error
 
 	return sessions, nil
 }
-func (r *SessionRepository) Save(session models.Session) error {
+func (r *SessionRepository) Save(ctx context.Context, session models.Session) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.store[session.SessionId] = session
+	r.save(session)
+
+	return nil
+}
+func (r *SessionRepository) UpdateLastSeen(ctx context.Context, sessionId string, lastSeenAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if _, ex := r.userSessionsMap[session.UserId]; !ex {
-		r.userSessionsMap[session.UserId] = make([]string, 0)
+	session, exist := r.store[sessionId]
+	if !exist {
+		return models.ErrNotFound
 	}
 
-	r.userSessionsMap[session.UserId] = append(r.userSessionsMap[session.UserId], session.SessionId)
+	session.LastSeenAt = lastSeenAt
+	r.store[sessionId] = session
 
 	return nil
 }
-func (r *SessionRepository) DeleteAllByUser(userId int64) error {
+func (r *SessionRepository) DeleteAllByUser(ctx context.Context, userId int64) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -66,29 +86,200 @@ func (r *SessionRepository) DeleteAllByUser(userId int64) error {
 
 	delete(r.userSessionsMap, userId)
 
+	for id, rt := range r.refreshTokens {
+		if rt.UserId == userId {
+			r.deleteRefreshToken(id)
+		}
+	}
+
 	return nil
 }
-func (r *SessionRepository) Delete(sessionId string) error {
+func (r *SessionRepository) Delete(ctx context.Context, sessionId string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	session, exist := r.store[sessionId]
+	if _, exist := r.store[sessionId]; !exist {
+		return models.ErrNotFound
+	}
+
+	// used token is kept until expiration to detect reuse
+	if id, exist := r.sessionRefresh[sessionId]; exist && !r.refreshTokens[id].Used {
+		r.deleteRefreshToken(id)
+	}
+
+	r.delete(sessionId)
+
+	return nil
+}
+func (r *SessionRepository) DeleteExpired(ctx context.Context, createdBefore, lastSeenBefore time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// refresh tokens outlive sessions, they are removed by DeleteExpiredRefreshTokens
+	deleted := 0
+	for sId, s := range r.store {
+		if s.CreatedAt.Before(createdBefore) || s.LastSeenAt.Before(lastSeenBefore) {
+			r.delete(sId)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (r *SessionRepository) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.saveRefreshToken(token)
+
+	return nil
+}
+func (r *SessionRepository) GetRefreshToken(ctx context.Context, id string) (*models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rt, exist := r.refreshTokens[id]
+	if !exist {
+		return nil, models.ErrNotFound
+	}
+
+	return &rt, nil
+}
+
+// UseRefreshToken marks token as used, only one of concurrent callers succeeds
+func (r *SessionRepository) UseRefreshToken(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.useRefreshToken(id)
+}
+
+// RotateRefreshToken uses token, replaces its session and saves new refresh token under one lock
+func (r *SessionRepository) RotateRefreshToken(ctx context.Context, usedId string, session models.Session, token models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.useRefreshToken(usedId); err != nil {
+		return err
+	}
+
+	if oldId := r.refreshTokens[usedId].SessionId; r.hasSession(oldId) {
+		r.delete(oldId)
+	}
+
+	r.save(session)
+	r.saveRefreshToken(token)
+
+	return nil
+}
+func (r *SessionRepository) DeleteFamily(ctx context.Context, familyId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for sId, s := range r.store {
+		if s.FamilyId == familyId {
+			r.delete(sId)
+		}
+	}
+
+	for id, rt := range r.refreshTokens {
+		if rt.FamilyId == familyId {
+			r.deleteRefreshToken(id)
+		}
+	}
+
+	return nil
+}
+func (r *SessionRepository) DeleteFamiliesExcept(ctx context.Context, userId int64, familyId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sId := range append([]string(nil), r.userSessionsMap[userId]...) {
+		if r.store[sId].FamilyId != familyId {
+			r.delete(sId)
+		}
+	}
+
+	for id, rt := range r.refreshTokens {
+		if rt.UserId == userId && rt.FamilyId != familyId {
+			r.deleteRefreshToken(id)
+		}
+	}
+
+	return nil
+}
+func (r *SessionRepository) DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for id, rt := range r.refreshTokens {
+		if rt.ExpiresAt.Before(before) {
+			r.deleteRefreshToken(id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (r *SessionRepository) hasSession(sessionId string) bool {
+	_, exist := r.store[sessionId]
+
+	return exist
+}
+
+func (r *SessionRepository) save(session models.Session) {
+	r.store[session.ID] = session
+	r.userSessionsMap[session.UserId] = append(r.userSessionsMap[session.UserId], session.ID)
+}
+
+func (r *SessionRepository) saveRefreshToken(token models.RefreshToken) {
+	r.refreshTokens[token.ID] = token
+	r.sessionRefresh[token.SessionId] = token.ID
+}
+
+func (r *SessionRepository) useRefreshToken(id string) error {
+	rt, exist := r.refreshTokens[id]
 	if !exist {
 		return models.ErrNotFound
 	}
 
+	if rt.Used {
+		return models.ErrAlreadyUsed
+	}
+
+	rt.Used = true
+	r.refreshTokens[id] = rt
+
+	return nil
+}
+
+// delete removes session only, its refresh token is left as is
+func (r *SessionRepository) delete(sessionId string) {
+	uId := r.store[sessionId].UserId
+
 	delete(r.store, sessionId)
 
-	uId := session.UserId
-	var ind int
 	for i, sId := range r.userSessionsMap[uId] {
 		if sId == sessionId {
-			ind = i
+			r.userSessionsMap[uId] = append(r.userSessionsMap[uId][:i:i], r.userSessionsMap[uId][i+1:]...)
 			break
 		}
 	}
 
-	r.userSessionsMap[uId] = append(r.userSessionsMap[uId][ind:], r.userSessionsMap[uId][:ind+1]...)
+	if len(r.userSessionsMap[uId]) == 0 {
+		delete(r.userSessionsMap, uId)
+	}
+}
 
-	return nil
+func (r *SessionRepository) deleteRefreshToken(id string) {
+	sId := r.refreshTokens[id].SessionId
+
+	delete(r.refreshTokens, id)
+
+	if r.sessionRefresh[sId] == id {
+		delete(r.sessionRefresh, sId)
+	}
 }
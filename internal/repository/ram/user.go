@@ -1,7 +1,7 @@
 package ram
 
 import (
-	"errors"
+	"context"
 	"rwa/internal/models"
 	"sync"
 	"sync/atomic"
@@ -26,7 +26,7 @@ func NewUserRepository() *UserRepository {
 	}
 }
 
-func (r *UserRepository) GetById(id int64) (*models.User, error) {
+func (r *UserRepository) GetById(ctx context.Context, id int64) (*models.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -38,11 +38,11 @@ func (r *UserRepository) GetById(id int64) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	id, exist := r.usernamesMap[username]
+	id, exist := r.usernamesMap[models.NormalizeIdentity(username)]
 	if !exist {
 		return nil, models.ErrNotFound
 	}
@@ -52,11 +52,11 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	id, exist := r.emailsMap[email]
+	id, exist := r.emailsMap[models.NormalizeIdentity(email)]
 	if !exist {
 		return nil, models.ErrNotFound
 	}
@@ -66,29 +66,24 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) Save(user models.User) (int64, error) {
+func (r *UserRepository) Save(ctx context.Context, user models.User) (int64, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	_, exist := r.usernamesMap[user.Username]
-	if exist {
-		return 0, errors.New("username already taken")
-	}
-	_, exist = r.emailsMap[user.Email]
-	if exist {
-		return 0, errors.New("username already taken")
+	if err := r.checkTaken(user); err != nil {
+		return 0, err
 	}
 
 	user.ID = r.newUserID()
 
 	r.store[user.ID] = user
-	r.usernamesMap[user.Username] = user.ID
-	r.emailsMap[user.Email] = user.ID
+	r.usernamesMap[models.NormalizeIdentity(user.Username)] = user.ID
+	r.emailsMap[models.NormalizeIdentity(user.Email)] = user.ID
 
 	return user.ID, nil
 }
 
-func (r *UserRepository) Update(user models.User) error {
+func (r *UserRepository) Update(ctx context.Context, user models.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -97,22 +92,37 @@ func (r *UserRepository) Update(user models.User) error {
 		return models.ErrNotFound
 	}
 
-	if currentUserState.Username != user.Username {
-		delete(r.usernamesMap, user.Username)
-		r.usernamesMap[user.Username] = user.ID
+	if err := r.checkTaken(user); err != nil {
+		return err
 	}
 
-	if currentUserState.Email != user.Email {
-		delete(r.emailsMap, user.Email)
-		r.emailsMap[user.Email] = user.ID
-	}
+	delete(r.usernamesMap, models.NormalizeIdentity(currentUserState.Username))
+	r.usernamesMap[models.NormalizeIdentity(user.Username)] = user.ID
+
+	delete(r.emailsMap, models.NormalizeIdentity(currentUserState.Email))
+	r.emailsMap[models.NormalizeIdentity(user.Email)] = user.ID
 
 	r.store[user.ID] = user
 
 	return nil
 }
 
-func (r *UserRepository) Delete(user models.User) error {
+func (r *UserRepository) ReplacePasswordHash(ctx context.Context, userId int64, oldHash, newHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exist := r.store[userId]
+	if !exist || user.HashedPassword != oldHash {
+		return models.ErrNotFound
+	}
+
+	user.HashedPassword = newHash
+	r.store[userId] = user
+
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, user models.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -122,12 +132,26 @@ func (r *UserRepository) Delete(user models.User) error {
 	}
 
 	delete(r.store, user.ID)
-	delete(r.usernamesMap, user.Username)
-	delete(r.emailsMap, user.Email)
+	delete(r.usernamesMap, models.NormalizeIdentity(user.Username))
+	delete(r.emailsMap, models.NormalizeIdentity(user.Email))
 
 	return nil
 }
 
+// checkTaken compares normalized username and email with other users
+func (r *UserRepository) checkTaken(user models.User) error {
+	errs := models.ValidationErrors{}
+
+	if id, exist := r.usernamesMap[models.NormalizeIdentity(user.Username)]; exist && id != user.ID {
+		errs.Add("username", "has already been taken")
+	}
+	if id, exist := r.emailsMap[models.NormalizeIdentity(user.Email)]; exist && id != user.ID {
+		errs.Add("email", "has already been taken")
+	}
+
+	return errs.Err()
+}
+
 func (r *UserRepository) newUserID() int64 {
 	return r.idCounter.Add(1)
 }
@@ -0,0 +1,85 @@
+package ram
+
+import (
+	"context"
+	"rwa/internal/models"
+	"sync"
+	"time"
+)
+
+type UserTokenRepository struct {
+	store map[string]models.UserToken
+	mu    *sync.RWMutex
+}
+
+func NewUserTokenRepository() *UserTokenRepository {
+	return &UserTokenRepository{
+		store: make(map[string]models.UserToken),
+		mu:    &sync.RWMutex{},
+	}
+}
+
+func (r *UserTokenRepository) Get(ctx context.Context, id string) (*models.UserToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	token, exist := r.store[id]
+	if !exist {
+		return nil, models.ErrNotFound
+	}
+
+	return &token, nil
+}
+
+// Replace checks previous tokens and saves new one under the same lock,
+// so concurrent callers cannot all pass the since check
+func (r *UserTokenRepository) Replace(ctx context.Context, token models.UserToken, since time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.store {
+		if t.UserId == token.UserId && t.Purpose == token.Purpose && !since.IsZero() && t.CreatedAt.After(since) {
+			return models.ErrTooManyAttempts
+		}
+	}
+
+	for id, t := range r.store {
+		if t.UserId == token.UserId && t.Purpose == token.Purpose {
+			delete(r.store, id)
+		}
+	}
+
+	r.store[token.ID] = token
+
+	return nil
+}
+
+// Delete returns models.ErrNotFound if token was already deleted,
+// so only one of concurrent callers can use token
+func (r *UserTokenRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exist := r.store[id]; !exist {
+		return models.ErrNotFound
+	}
+
+	delete(r.store, id)
+
+	return nil
+}
+
+func (r *UserTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for id, token := range r.store {
+		if token.ExpiresAt.Before(before) {
+			delete(r.store, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,1200 @@
+// Package repotest is conformance suite which every repository backend has to pass
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rwa/internal/models"
+	"rwa/internal/services"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Repositories of one backend, articles and sessions reference users of the same backend.
+// Comments and Follows are optional, their checks are skipped when backend has none
+type Repositories struct {
+	Users    services.UserRepository
+	Sessions services.SessionRepository
+	Articles services.ArticleRepository
+	Comments services.CommentRepository
+	Follows  services.FollowRepository
+}
+
+// Factory returns empty repositories, every test gets its own ones
+type Factory func(t *testing.T) Repositories
+
+// base time is whole in microseconds, so it is stored precisely by every backend
+var base = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// Run runs all checks, concurrent ones are meant to be run with -race
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newRepos) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, newRepos) })
+	t.Run("Articles", func(t *testing.T) { testArticles(t, newRepos) })
+	t.Run("Comments", func(t *testing.T) { testComments(t, newRepos) })
+	t.Run("Follows", func(t *testing.T) { testFollows(t, newRepos) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newRepos) })
+}
+
+func testUsers(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepos(t).Users
+
+		if _, err := repo.GetById(ctx, 1); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("GetById: want ErrNotFound, have: %v", err)
+		}
+		if _, err := repo.GetByUsername(ctx, "golang"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("GetByUsername: want ErrNotFound, have: %v", err)
+		}
+		if _, err := repo.GetByEmail(ctx, "golang@example.com"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("GetByEmail: want ErrNotFound, have: %v", err)
+		}
+		if err := repo.Update(ctx, models.User{ID: 1, Username: "golang", Email: "golang@example.com"}); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("Update: want ErrNotFound, have: %v", err)
+		}
+		if err := repo.Delete(ctx, models.User{ID: 1}); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("Delete: want ErrNotFound, have: %v", err)
+		}
+	})
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		repo := newRepos(t).Users
+
+		want := newUser("GoLang")
+		want.Bio = "bio"
+		want.Verified = true
+		want.ID = mustSaveUser(t, repo, want)
+
+		other := mustSaveUser(t, repo, newUser("other"))
+		if other == want.ID {
+			t.Fatalf("users got the same id %d", other)
+		}
+
+		for name, get := range map[string]func() (*models.User, error){
+			"GetById":       func() (*models.User, error) { return repo.GetById(ctx, want.ID) },
+			"GetByUsername": func() (*models.User, error) { return repo.GetByUsername(ctx, "GOLANG") },
+			"GetByEmail":    func() (*models.User, error) { return repo.GetByEmail(ctx, "GoLang@Example.com") },
+		} {
+			have, err := get()
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			checkUser(t, name, want, *have)
+		}
+	})
+
+	t.Run("UniqueUsernameAndEmail", func(t *testing.T) {
+		repo := newRepos(t).Users
+
+		mustSaveUser(t, repo, newUser("golang"))
+
+		u := newUser("GOLANG")
+		u.Email = "other@example.com"
+		_, err := repo.Save(ctx, u)
+		checkTaken(t, "username", err)
+
+		u = newUser("other")
+		u.Email = "GOLANG@example.com"
+		_, err = repo.Save(ctx, u)
+		checkTaken(t, "email", err)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepos(t).Users
+
+		u := newUser("golang")
+		u.ID = mustSaveUser(t, repo, u)
+		mustSaveUser(t, repo, newUser("taken"))
+
+		u.Username = "gopher"
+		u.Email = "gopher@example.com"
+		u.Bio = "new bio"
+		if err := repo.Update(ctx, u); err != nil {
+			t.Fatalf("Update: unexpected error: %v", err)
+		}
+
+		// old username and email are free again
+		if _, err := repo.GetByUsername(ctx, "golang"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("old username: want ErrNotFound, have: %v", err)
+		}
+		if _, err := repo.GetByEmail(ctx, "golang@example.com"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("old email: want ErrNotFound, have: %v", err)
+		}
+
+		have, err := repo.GetByUsername(ctx, "gopher")
+		if err != nil {
+			t.Fatalf("new username: unexpected error: %v", err)
+		}
+		checkUser(t, "GetByUsername", u, *have)
+
+		// saving unchanged user is not a conflict with itself
+		if err := repo.Update(ctx, u); err != nil {
+			t.Errorf("unchanged Update: unexpected error: %v", err)
+		}
+
+		taken := u
+		taken.Username = "TAKEN"
+		checkTaken(t, "username", repo.Update(ctx, taken))
+
+		if _, err := repo.Save(ctx, newUser("golang")); err != nil {
+			t.Errorf("Save with freed username: unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ReplacePasswordHash", func(t *testing.T) {
+		repo := newRepos(t).Users
+
+		want := newUser("golang")
+		want.Bio = "bio"
+		want.ID = mustSaveUser(t, repo, want)
+
+		if err := repo.ReplacePasswordHash(ctx, want.ID, "stale", "new"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("stale hash: want ErrNotFound, have: %v", err)
+		}
+		if err := repo.ReplacePasswordHash(ctx, want.ID+1, want.HashedPassword, "new"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("missing user: want ErrNotFound, have: %v", err)
+		}
+
+		if err := repo.ReplacePasswordHash(ctx, want.ID, want.HashedPassword, "new"); err != nil {
+			t.Fatalf("ReplacePasswordHash: unexpected error: %v", err)
+		}
+
+		want.HashedPassword = "new"
+		have, err := repo.GetById(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("GetById: unexpected error: %v", err)
+		}
+		checkUser(t, "GetById", want, *have)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepos(t).Users
+
+		u := newUser("golang")
+		u.ID = mustSaveUser(t, repo, u)
+
+		if err := repo.Delete(ctx, u); err != nil {
+			t.Fatalf("Delete: unexpected error: %v", err)
+		}
+		if _, err := repo.GetById(ctx, u.ID); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("GetById: want ErrNotFound, have: %v", err)
+		}
+		if err := repo.Delete(ctx, u); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("second Delete: want ErrNotFound, have: %v", err)
+		}
+		if _, err := repo.Save(ctx, newUser("golang")); err != nil {
+			t.Errorf("Save with freed username: unexpected error: %v", err)
+		}
+	})
+}
+
+func testSessions(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepos(t).Sessions
+
+		if _, err := repo.Get(ctx, "missing"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("Get: want ErrNotFound, have: %v", err)
+		}
+		if err := repo.UpdateLastSeen(ctx, "missing", base); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("UpdateLastSeen: want ErrNotFound, have: %v", err)
+		}
+		if err := repo.Delete(ctx, "missing"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("Delete: want ErrNotFound, have: %v", err)
+		}
+		if _, err := repo.GetRefreshToken(ctx, "missing"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("GetRefreshToken: want ErrNotFound, have: %v", err)
+		}
+		if err := repo.UseRefreshToken(ctx, "missing"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("UseRefreshToken: want ErrNotFound, have: %v", err)
+		}
+		checkNoSessions(t, repo, 1)
+	})
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Sessions
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+
+		want := newSession("s1", userId, "f1")
+		mustSaveSession(t, repo, want)
+		mustSaveSession(t, repo, newSession("s2", userId, "f2"))
+
+		have, err := repo.Get(ctx, "s1")
+		if err != nil {
+			t.Fatalf("Get: unexpected error: %v", err)
+		}
+		checkSession(t, want, *have)
+
+		sessions, err := repo.GetAllByUser(ctx, userId)
+		if err != nil || len(sessions) != 2 {
+			t.Fatalf("GetAllByUser: want 2 sessions, have: %d, %v", len(sessions), err)
+		}
+
+		lastSeen := base.Add(time.Hour)
+		if err := repo.UpdateLastSeen(ctx, "s1", lastSeen); err != nil {
+			t.Fatalf("UpdateLastSeen: unexpected error: %v", err)
+		}
+		have, _ = repo.Get(ctx, "s1")
+		if !have.LastSeenAt.Equal(lastSeen) {
+			t.Errorf("LastSeenAt: want %v, have %v", lastSeen, have.LastSeenAt)
+		}
+	})
+
+	t.Run("DeleteAllByUserCascade", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Sessions
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+		otherId := mustSaveUser(t, repos.Users, newUser("other"))
+
+		mustSaveSession(t, repo, newSession("s1", userId, "f1"))
+		mustSaveSession(t, repo, newSession("s2", userId, "f2"))
+		mustSaveSession(t, repo, newSession("s3", otherId, "f3"))
+		mustSaveRefreshToken(t, repo, newRefreshToken("r1", "s1", userId, "f1"))
+		mustSaveRefreshToken(t, repo, newRefreshToken("r2", "s2", userId, "f2"))
+		mustSaveRefreshToken(t, repo, newRefreshToken("r3", "s3", otherId, "f3"))
+		mustUseRefreshToken(t, repo, "r2")
+
+		if err := repo.DeleteAllByUser(ctx, userId); err != nil {
+			t.Fatalf("DeleteAllByUser: unexpected error: %v", err)
+		}
+
+		checkNoSessions(t, repo, userId)
+		for _, id := range []string{"s1", "s2"} {
+			if _, err := repo.Get(ctx, id); !errors.Is(err, models.ErrNotFound) {
+				t.Errorf("Get %s: want ErrNotFound, have: %v", id, err)
+			}
+		}
+		// used refresh tokens go away with user sessions too
+		for _, id := range []string{"r1", "r2"} {
+			if _, err := repo.GetRefreshToken(ctx, id); !errors.Is(err, models.ErrNotFound) {
+				t.Errorf("GetRefreshToken %s: want ErrNotFound, have: %v", id, err)
+			}
+		}
+
+		if _, err := repo.Get(ctx, "s3"); err != nil {
+			t.Errorf("session of other user: unexpected error: %v", err)
+		}
+		if _, err := repo.GetRefreshToken(ctx, "r3"); err != nil {
+			t.Errorf("refresh token of other user: unexpected error: %v", err)
+		}
+	})
+
+	t.Run("DeleteKeepsUsedRefreshTokens", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Sessions
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+
+		mustSaveSession(t, repo, newSession("s1", userId, "f1"))
+		mustSaveSession(t, repo, newSession("s2", userId, "f1"))
+		mustSaveRefreshToken(t, repo, newRefreshToken("r1", "s1", userId, "f1"))
+		mustSaveRefreshToken(t, repo, newRefreshToken("r2", "s2", userId, "f1"))
+		mustUseRefreshToken(t, repo, "r1")
+
+		for _, id := range []string{"s1", "s2"} {
+			if err := repo.Delete(ctx, id); err != nil {
+				t.Fatalf("Delete %s: unexpected error: %v", id, err)
+			}
+		}
+
+		// used token is needed to detect its reuse
+		rt, err := repo.GetRefreshToken(ctx, "r1")
+		if err != nil || !rt.Used {
+			t.Errorf("used token: want it kept, have: %+v, %v", rt, err)
+		}
+		if _, err := repo.GetRefreshToken(ctx, "r2"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("unused token: want ErrNotFound, have: %v", err)
+		}
+
+		if err := repo.DeleteFamily(ctx, "f1"); err != nil {
+			t.Fatalf("DeleteFamily: unexpected error: %v", err)
+		}
+		if _, err := repo.GetRefreshToken(ctx, "r1"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("token of deleted family: want ErrNotFound, have: %v", err)
+		}
+	})
+
+	t.Run("UseRefreshToken", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Sessions
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+
+		want := newRefreshToken("r1", "s1", userId, "f1")
+		mustSaveSession(t, repo, newSession("s1", userId, "f1"))
+		mustSaveRefreshToken(t, repo, want)
+
+		have, err := repo.GetRefreshToken(ctx, "r1")
+		if err != nil {
+			t.Fatalf("GetRefreshToken: unexpected error: %v", err)
+		}
+		if have.SessionId != want.SessionId || have.FamilyId != want.FamilyId || have.UserId != want.UserId ||
+			string(have.TokenHash) != string(want.TokenHash) || have.Used || !have.ExpiresAt.Equal(want.ExpiresAt) {
+			t.Errorf("GetRefreshToken: want %+v, have %+v", want, *have)
+		}
+
+		mustUseRefreshToken(t, repo, "r1")
+		if err := repo.UseRefreshToken(ctx, "r1"); !errors.Is(err, models.ErrAlreadyUsed) {
+			t.Errorf("second use: want ErrAlreadyUsed, have: %v", err)
+		}
+	})
+
+	t.Run("DeleteFamily", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Sessions
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+
+		mustSaveSession(t, repo, newSession("s1", userId, "f1"))
+		mustSaveSession(t, repo, newSession("s2", userId, "f1"))
+		mustSaveSession(t, repo, newSession("s3", userId, "f2"))
+
+		if err := repo.DeleteFamily(ctx, "f1"); err != nil {
+			t.Fatalf("DeleteFamily: unexpected error: %v", err)
+		}
+
+		sessions, err := repo.GetAllByUser(ctx, userId)
+		if err != nil || len(sessions) != 1 || sessions[0].ID != "s3" {
+			t.Errorf("GetAllByUser: want only s3, have: %v, %v", sessions, err)
+		}
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Sessions
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+
+		old := newSession("old", userId, "f1")
+		old.CreatedAt = base.Add(-48 * time.Hour)
+		idle := newSession("idle", userId, "f2")
+		idle.LastSeenAt = base.Add(-2 * time.Hour)
+		fresh := newSession("fresh", userId, "f3")
+
+		for _, s := range []models.Session{old, idle, fresh} {
+			mustSaveSession(t, repo, s)
+		}
+
+		deleted, err := repo.DeleteExpired(ctx, base.Add(-24*time.Hour), base.Add(-time.Hour))
+		if err != nil || deleted != 2 {
+			t.Fatalf("DeleteExpired: want 2 deleted, have: %d, %v", deleted, err)
+		}
+		if _, err := repo.Get(ctx, "fresh"); err != nil {
+			t.Errorf("fresh session: unexpected error: %v", err)
+		}
+
+		expired := newRefreshToken("r1", "fresh", userId, "f3")
+		expired.ExpiresAt = base.Add(-time.Minute)
+		mustSaveRefreshToken(t, repo, expired)
+		mustSaveRefreshToken(t, repo, newRefreshToken("r2", "fresh", userId, "f3"))
+
+		deleted, err = repo.DeleteExpiredRefreshTokens(ctx, base)
+		if err != nil || deleted != 1 {
+			t.Fatalf("DeleteExpiredRefreshTokens: want 1 deleted, have: %d, %v", deleted, err)
+		}
+		if _, err := repo.GetRefreshToken(ctx, "r2"); err != nil {
+			t.Errorf("valid refresh token: unexpected error: %v", err)
+		}
+	})
+	t.Run("RefreshTokenOutlivesExpiredSession", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Sessions
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+
+		idle := newSession("idle", userId, "f1")
+		idle.LastSeenAt = base.Add(-2 * time.Hour)
+		mustSaveSession(t, repo, idle)
+		mustSaveRefreshToken(t, repo, newRefreshToken("r1", "idle", userId, "f1"))
+
+		deleted, err := repo.DeleteExpired(ctx, base.Add(-24*time.Hour), base.Add(-time.Hour))
+		if err != nil || deleted != 1 {
+			t.Fatalf("DeleteExpired: want 1 deleted, have: %d, %v", deleted, err)
+		}
+
+		// client refreshes after its access session expired, within RefreshTTL
+		rt, err := repo.GetRefreshToken(ctx, "r1")
+		if err != nil || rt.Used {
+			t.Fatalf("refresh token of expired session: want it kept unused, have: %+v, %v", rt, err)
+		}
+		if err := repo.UseRefreshToken(ctx, "r1"); err != nil {
+			t.Fatalf("UseRefreshToken: unexpected error: %v", err)
+		}
+	})
+	t.Run("RotateRefreshToken", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Sessions
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+
+		mustSaveSession(t, repo, newSession("s1", userId, "f1"))
+		mustSaveRefreshToken(t, repo, newRefreshToken("r1", "s1", userId, "f1"))
+
+		next := newSession("s2", userId, "f1")
+		if err := repo.RotateRefreshToken(ctx, "r1", next, newRefreshToken("r2", "s2", userId, "f1")); err != nil {
+			t.Fatalf("RotateRefreshToken: unexpected error: %v", err)
+		}
+
+		if _, err := repo.Get(ctx, "s1"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("replaced session: want ErrNotFound, have: %v", err)
+		}
+		have, err := repo.Get(ctx, "s2")
+		if err != nil {
+			t.Fatalf("new session: unexpected error: %v", err)
+		}
+		checkSession(t, next, *have)
+		if rt, err := repo.GetRefreshToken(ctx, "r1"); err != nil || !rt.Used {
+			t.Errorf("rotated token: want it kept used, have: %+v, %v", rt, err)
+		}
+		if rt, err := repo.GetRefreshToken(ctx, "r2"); err != nil || rt.Used {
+			t.Errorf("new token: want it unused, have: %+v, %v", rt, err)
+		}
+
+		// reuse changes nothing
+		err = repo.RotateRefreshToken(ctx, "r1", newSession("s3", userId, "f1"), newRefreshToken("r3", "s3", userId, "f1"))
+		if !errors.Is(err, models.ErrAlreadyUsed) {
+			t.Fatalf("reused token: want ErrAlreadyUsed, have: %v", err)
+		}
+		if _, err := repo.Get(ctx, "s3"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("session of reused token: want ErrNotFound, have: %v", err)
+		}
+		if _, err := repo.GetRefreshToken(ctx, "r3"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("refresh token of reused token: want ErrNotFound, have: %v", err)
+		}
+
+		err = repo.RotateRefreshToken(ctx, "missing", newSession("s4", userId, "f1"), newRefreshToken("r4", "s4", userId, "f1"))
+		if !errors.Is(err, models.ErrNotFound) {
+			t.Fatalf("missing token: want ErrNotFound, have: %v", err)
+		}
+	})
+	t.Run("DeleteFamiliesExcept", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Sessions
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+		otherId := mustSaveUser(t, repos.Users, newUser("other"))
+
+		mustSaveSession(t, repo, newSession("current", userId, "f1"))
+		mustSaveRefreshToken(t, repo, newRefreshToken("r1", "current", userId, "f1"))
+		mustSaveSession(t, repo, newSession("s2", userId, "f2"))
+		mustSaveRefreshToken(t, repo, newRefreshToken("r2", "s2", userId, "f2"))
+		// refresh token of session which was already swept
+		mustSaveRefreshToken(t, repo, newRefreshToken("r3", "swept", userId, "f3"))
+		mustSaveSession(t, repo, newSession("s4", otherId, "f4"))
+		mustSaveRefreshToken(t, repo, newRefreshToken("r4", "s4", otherId, "f4"))
+
+		if err := repo.DeleteFamiliesExcept(ctx, userId, "f1"); err != nil {
+			t.Fatalf("DeleteFamiliesExcept: unexpected error: %v", err)
+		}
+
+		for _, id := range []string{"current", "s4"} {
+			if _, err := repo.Get(ctx, id); err != nil {
+				t.Errorf("session %s: unexpected error: %v", id, err)
+			}
+		}
+		if _, err := repo.Get(ctx, "s2"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("session s2: want ErrNotFound, have: %v", err)
+		}
+		for _, id := range []string{"r1", "r4"} {
+			if _, err := repo.GetRefreshToken(ctx, id); err != nil {
+				t.Errorf("refresh token %s: unexpected error: %v", id, err)
+			}
+		}
+		for _, id := range []string{"r2", "r3"} {
+			if _, err := repo.GetRefreshToken(ctx, id); !errors.Is(err, models.ErrNotFound) {
+				t.Errorf("refresh token %s: want ErrNotFound, have: %v", id, err)
+			}
+		}
+	})
+
+}
+
+func testArticles(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepos(t).Articles
+
+		if _, err := repo.GetBySlug(ctx, "missing"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("GetBySlug: want ErrNotFound, have: %v", err)
+		}
+		if err := repo.Update(ctx, "missing", models.Article{Slug: "missing"}); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("Update: want ErrNotFound, have: %v", err)
+		}
+		if err := repo.Delete(ctx, models.Article{Slug: "missing"}); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("Delete: want ErrNotFound, have: %v", err)
+		}
+	})
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		repos := newRepos(t)
+		author := mustSaveProfile(t, repos.Users, "golang")
+
+		want := newArticle("a1", author, 0, "go", "web")
+		mustSaveArticle(t, repos.Articles, want)
+
+		have, err := repos.Articles.GetBySlug(ctx, "a1")
+		if err != nil {
+			t.Fatalf("GetBySlug: unexpected error: %v", err)
+		}
+		checkArticle(t, want, *have)
+	})
+
+	t.Run("UniqueSlug", func(t *testing.T) {
+		repos := newRepos(t)
+		author := mustSaveProfile(t, repos.Users, "golang")
+
+		mustSaveArticle(t, repos.Articles, newArticle("a1", author, 0, "go"))
+		mustSaveArticle(t, repos.Articles, newArticle("a2", author, 1, "web"))
+
+		if err := repos.Articles.Save(ctx, newArticle("a1", author, 2, "other")); err == nil {
+			t.Errorf("Save with taken slug: want error")
+		}
+		if err := repos.Articles.Update(ctx, "a2", newArticle("a1", author, 1, "other")); err == nil {
+			t.Errorf("Update to taken slug: want error")
+		}
+
+		// failed writes leave articles and tags untouched
+		checkSlugs(t, repos.Articles, models.ArticleFilter{}, "a2", "a1")
+		checkTags(t, repos.Articles, map[string]int{"go": 1, "web": 1})
+	})
+
+	t.Run("MixedCaseTags", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Articles
+		author := mustSaveProfile(t, repos.Users, "golang")
+
+		mustSaveArticle(t, repo, newArticle("lower", author, 1, "go"))
+		mustSaveArticle(t, repo, newArticle("upper", author, 2, "Go"))
+
+		checkTags(t, repo, map[string]int{"go": 1, "Go": 1})
+		checkSlugs(t, repo, models.ArticleFilter{Tags: []string{"Go"}}, "upper")
+	})
+
+	t.Run("MixedCaseSlugs", func(t *testing.T) {
+		repos := newRepos(t)
+		repo := repos.Articles
+		author := mustSaveProfile(t, repos.Users, "golang")
+
+		mustSaveArticle(t, repo, newArticle("go", author, 1, "lower"))
+		mustSaveArticle(t, repo, newArticle("Go", author, 2, "upper"))
+
+		have, err := repo.GetBySlug(ctx, "Go")
+		if err != nil || have.Slug != "Go" {
+			t.Fatalf("GetBySlug: want article Go, have: %+v, %v", have, err)
+		}
+
+		if err := repo.Delete(ctx, models.Article{Slug: "Go"}); err != nil {
+			t.Fatalf("Delete: unexpected error: %v", err)
+		}
+		checkSlugs(t, repo, models.ArticleFilter{}, "go")
+	})
+
+	t.Run("TagsAfterUpdateAndDelete", func(t *testing.T) {
+		repos := newRepos(t)
+		author := mustSaveProfile(t, repos.Users, "golang")
+
+		mustSaveArticle(t, repos.Articles, newArticle("a1", author, 0, "go", "web"))
+		mustSaveArticle(t, repos.Articles, newArticle("a2", author, 1, "go"))
+		checkTags(t, repos.Articles, map[string]int{"go": 2, "web": 1})
+
+		updated := newArticle("a1-new", author, 0, "web", "db")
+		if err := repos.Articles.Update(ctx, "a1", updated); err != nil {
+			t.Fatalf("Update: unexpected error: %v", err)
+		}
+		checkTags(t, repos.Articles, map[string]int{"go": 1, "web": 1, "db": 1})
+		checkSlugs(t, repos.Articles, models.ArticleFilter{Tags: []string{"go"}}, "a2")
+		checkSlugs(t, repos.Articles, models.ArticleFilter{Tags: []string{"db"}}, "a1-new")
+
+		if _, err := repos.Articles.GetBySlug(ctx, "a1"); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("old slug: want ErrNotFound, have: %v", err)
+		}
+		have, err := repos.Articles.GetBySlug(ctx, "a1-new")
+		if err != nil {
+			t.Fatalf("new slug: unexpected error: %v", err)
+		}
+		checkArticle(t, updated, *have)
+
+		if err := repos.Articles.Delete(ctx, updated); err != nil {
+			t.Fatalf("Delete: unexpected error: %v", err)
+		}
+		checkTags(t, repos.Articles, map[string]int{"go": 1})
+		checkSlugs(t, repos.Articles, models.ArticleFilter{Tags: []string{"web", "db"}})
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		repos := newRepos(t)
+		golang := mustSaveProfile(t, repos.Users, "golang")
+		rust := mustSaveProfile(t, repos.Users, "rust")
+
+		mustSaveArticle(t, repos.Articles, newArticle("a1", golang, 0, "go", "web"))
+		mustSaveArticle(t, repos.Articles, newArticle("a2", rust, 1, "rust", "web"))
+		mustSaveArticle(t, repos.Articles, newArticle("a3", golang, 2, "go"))
+		// the same time as a3, slug breaks the tie
+		mustSaveArticle(t, repos.Articles, newArticle("a4", rust, 2, "rust", "db"))
+
+		checkSlugs(t, repos.Articles, models.ArticleFilter{}, "a4", "a3", "a2", "a1")
+		checkSlugs(t, repos.Articles, models.ArticleFilter{AuthorId: golang.ID}, "a3", "a1")
+		checkSlugs(t, repos.Articles, models.ArticleFilter{Tags: []string{"go", "db"}}, "a4", "a3", "a1")
+		checkSlugs(t, repos.Articles, models.ArticleFilter{Tags: []string{"rust", "web"}, TagMode: models.TagModeAll}, "a2")
+		checkSlugs(t, repos.Articles, models.ArticleFilter{Tags: []string{"web"}, ExcludeTags: []string{"rust"}}, "a1")
+		checkSlugs(t, repos.Articles, models.ArticleFilter{CreatedFrom: at(1), CreatedTo: at(2)}, "a4", "a3", "a2")
+
+		articles, total, err := repos.Articles.Find(ctx, models.ArticleFilter{}, models.Page{Limit: 2, Offset: 1})
+		if err != nil || total != 4 || !slices.Equal(slugsOf(articles), []string{"a3", "a2"}) {
+			t.Errorf("offset page: want [a3 a2] of 4, have: %v of %d, %v", slugsOf(articles), total, err)
+		}
+
+		after := models.ArticleCursor{CreatedAt: at(2), Slug: "a3"}
+		articles, total, err = repos.Articles.Find(ctx, models.ArticleFilter{}, models.Page{Limit: 10, After: &after})
+		if err != nil || total != 4 || !slices.Equal(slugsOf(articles), []string{"a2", "a1"}) {
+			t.Errorf("keyset page: want [a2 a1] of 4, have: %v of %d, %v", slugsOf(articles), total, err)
+		}
+	})
+
+	t.Run("GetAllByUsers", func(t *testing.T) {
+		repos := newRepos(t)
+		golang := mustSaveProfile(t, repos.Users, "golang")
+		rust := mustSaveProfile(t, repos.Users, "rust")
+		java := mustSaveProfile(t, repos.Users, "java")
+
+		mustSaveArticle(t, repos.Articles, newArticle("a1", golang, 0))
+		mustSaveArticle(t, repos.Articles, newArticle("a2", java, 1))
+		mustSaveArticle(t, repos.Articles, newArticle("a3", rust, 2))
+		mustSaveArticle(t, repos.Articles, newArticle("a4", golang, 3))
+
+		articles, total, err := repos.Articles.GetAllByUsers(ctx, []int64{golang.ID, rust.ID}, models.Page{Limit: 2})
+		if err != nil || total != 3 || !slices.Equal(slugsOf(articles), []string{"a4", "a3"}) {
+			t.Errorf("first page: want [a4 a3] of 3, have: %v of %d, %v", slugsOf(articles), total, err)
+		}
+
+		after := articles[1].Cursor()
+		articles, _, err = repos.Articles.GetAllByUsers(ctx, []int64{golang.ID, rust.ID}, models.Page{Limit: 2, After: &after})
+		if err != nil || !slices.Equal(slugsOf(articles), []string{"a1"}) {
+			t.Errorf("next page: want [a1], have: %v, %v", slugsOf(articles), err)
+		}
+
+		articles, total, err = repos.Articles.GetAllByUsers(ctx, nil, models.Page{Limit: 2})
+		if err != nil || total != 0 || len(articles) != 0 {
+			t.Errorf("no users: want nothing, have: %v of %d, %v", slugsOf(articles), total, err)
+		}
+	})
+}
+
+func testComments(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	if newRepos(t).Comments == nil {
+		t.Skip("backend has no comment repository")
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepos(t).Comments
+
+		if _, err := repo.GetById(ctx, 1); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("GetById: want ErrNotFound, have: %v", err)
+		}
+		if err := repo.Delete(ctx, models.Comment{ID: 1}); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("Delete: want ErrNotFound, have: %v", err)
+		}
+
+		comments, err := repo.GetAllByArticle(ctx, "missing")
+		if err != nil || comments == nil || len(comments) != 0 {
+			t.Errorf("GetAllByArticle: want empty slice, have: %v, %v", comments, err)
+		}
+	})
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		repos := newRepos(t)
+		author := mustSaveProfile(t, repos.Users, "golang")
+		mustSaveArticle(t, repos.Articles, newArticle("a1", author, 0))
+		mustSaveArticle(t, repos.Articles, newArticle("a2", author, 1))
+
+		first := mustSaveComment(t, repos.Comments, newComment("a1", author, 0))
+		second := mustSaveComment(t, repos.Comments, newComment("a1", author, 1))
+		mustSaveComment(t, repos.Comments, newComment("a2", author, 2))
+
+		have, err := repos.Comments.GetById(ctx, first.ID)
+		if err != nil {
+			t.Fatalf("GetById: unexpected error: %v", err)
+		}
+		checkComment(t, first, *have)
+
+		checkComments(t, repos.Comments, "a1", first, second)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repos := newRepos(t)
+		author := mustSaveProfile(t, repos.Users, "golang")
+		mustSaveArticle(t, repos.Articles, newArticle("a1", author, 0))
+
+		first := mustSaveComment(t, repos.Comments, newComment("a1", author, 0))
+		second := mustSaveComment(t, repos.Comments, newComment("a1", author, 1))
+
+		if err := repos.Comments.Delete(ctx, first); err != nil {
+			t.Fatalf("Delete: unexpected error: %v", err)
+		}
+		if _, err := repos.Comments.GetById(ctx, first.ID); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("GetById of deleted comment: want ErrNotFound, have: %v", err)
+		}
+		checkComments(t, repos.Comments, "a1", second)
+
+		if err := repos.Comments.DeleteAllByArticle(ctx, "a1"); err != nil {
+			t.Fatalf("DeleteAllByArticle: unexpected error: %v", err)
+		}
+		checkComments(t, repos.Comments, "a1")
+	})
+
+	t.Run("UpdateSlug", func(t *testing.T) {
+		repos := newRepos(t)
+		author := mustSaveProfile(t, repos.Users, "golang")
+		article := newArticle("a1", author, 0)
+		mustSaveArticle(t, repos.Articles, article)
+
+		c := mustSaveComment(t, repos.Comments, newComment("a1", author, 0))
+
+		article.Slug = "b1"
+		if err := repos.Articles.Update(ctx, "a1", article); err != nil {
+			t.Fatalf("Update article: unexpected error: %v", err)
+		}
+		if err := repos.Comments.UpdateSlug(ctx, "a1", "b1"); err != nil {
+			t.Fatalf("UpdateSlug: unexpected error: %v", err)
+		}
+
+		c.ArticleSlug = "b1"
+		checkComments(t, repos.Comments, "b1", c)
+		checkComments(t, repos.Comments, "a1")
+	})
+}
+
+func testFollows(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	if newRepos(t).Follows == nil {
+		t.Skip("backend has no follow repository")
+	}
+
+	t.Run("FollowAndUnfollow", func(t *testing.T) {
+		repos := newRepos(t)
+		follower := mustSaveProfile(t, repos.Users, "follower")
+		first := mustSaveProfile(t, repos.Users, "first")
+		second := mustSaveProfile(t, repos.Users, "second")
+
+		for _, id := range []int64{first.ID, first.ID, second.ID} {
+			if err := repos.Follows.Follow(ctx, follower.ID, id); err != nil {
+				t.Fatalf("Follow %d: unexpected error: %v", id, err)
+			}
+		}
+		checkFollowees(t, repos.Follows, follower.ID, first.ID, second.ID)
+		checkFollowees(t, repos.Follows, first.ID)
+
+		following, err := repos.Follows.IsFollowing(ctx, follower.ID, first.ID)
+		if err != nil || !following {
+			t.Errorf("IsFollowing: want true, have: %v, %v", following, err)
+		}
+		following, err = repos.Follows.IsFollowing(ctx, first.ID, follower.ID)
+		if err != nil || following {
+			t.Errorf("IsFollowing of reverse pair: want false, have: %v, %v", following, err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := repos.Follows.Unfollow(ctx, follower.ID, first.ID); err != nil {
+				t.Fatalf("Unfollow: unexpected error: %v", err)
+			}
+		}
+		checkFollowees(t, repos.Follows, follower.ID, second.ID)
+	})
+}
+
+func testConcurrency(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	const workers = 8
+
+	t.Run("SameUsername", func(t *testing.T) {
+		repo := newRepos(t).Users
+
+		saved := runConcurrently(workers, func(i int) error {
+			u := newUser("golang")
+			u.Email = fmt.Sprintf("golang%d@example.com", i)
+			_, err := repo.Save(ctx, u)
+			return err
+		})
+		if saved != 1 {
+			t.Errorf("want exactly one saved user, have %d", saved)
+		}
+	})
+
+	t.Run("SameSlug", func(t *testing.T) {
+		repos := newRepos(t)
+		author := mustSaveProfile(t, repos.Users, "golang")
+
+		saved := runConcurrently(workers, func(i int) error {
+			return repos.Articles.Save(ctx, newArticle("a1", author, i, fmt.Sprintf("t%d", i)))
+		})
+		if saved != 1 {
+			t.Errorf("want exactly one saved article, have %d", saved)
+		}
+
+		// tags of rejected articles are not counted
+		tags, err := repos.Articles.Tags(ctx)
+		if err != nil || len(tags) != 1 {
+			t.Errorf("Tags: want one tag, have: %v, %v", tags, err)
+		}
+	})
+
+	t.Run("UseRefreshToken", func(t *testing.T) {
+		repos := newRepos(t)
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+		mustSaveSession(t, repos.Sessions, newSession("s1", userId, "f1"))
+		mustSaveRefreshToken(t, repos.Sessions, newRefreshToken("r1", "s1", userId, "f1"))
+
+		used := runConcurrently(workers, func(int) error {
+			return repos.Sessions.UseRefreshToken(ctx, "r1")
+		})
+		if used != 1 {
+			t.Errorf("want exactly one successful use, have %d", used)
+		}
+	})
+
+	t.Run("RotateRefreshToken", func(t *testing.T) {
+		repos := newRepos(t)
+		userId := mustSaveUser(t, repos.Users, newUser("golang"))
+		mustSaveSession(t, repos.Sessions, newSession("s1", userId, "f1"))
+		mustSaveRefreshToken(t, repos.Sessions, newRefreshToken("r1", "s1", userId, "f1"))
+
+		rotated := runConcurrently(workers, func(i int) error {
+			sessionId := fmt.Sprintf("s%d", i+2)
+			return repos.Sessions.RotateRefreshToken(ctx, "r1",
+				newSession(sessionId, userId, "f1"), newRefreshToken(fmt.Sprintf("r%d", i+2), sessionId, userId, "f1"))
+		})
+		if rotated != 1 {
+			t.Errorf("want exactly one successful rotation, have %d", rotated)
+		}
+
+		sessions, err := repos.Sessions.GetAllByUser(ctx, userId)
+		if err != nil || len(sessions) != 1 {
+			t.Errorf("want one session after rotation, have: %d, %v", len(sessions), err)
+		}
+	})
+
+	t.Run("ArticlesAndTags", func(t *testing.T) {
+		repos := newRepos(t)
+		author := mustSaveProfile(t, repos.Users, "golang")
+
+		for i := 0; i < workers; i++ {
+			mustSaveArticle(t, repos.Articles, newArticle(fmt.Sprintf("a%d", i), author, i, "old"))
+		}
+
+		// every article is moved from "old" tag to "new" one while others are read and deleted
+		runConcurrently(workers, func(i int) error {
+			slug := fmt.Sprintf("a%d", i)
+			if i%2 == 0 {
+				return repos.Articles.Delete(ctx, models.Article{Slug: slug})
+			}
+
+			if err := repos.Articles.Update(ctx, slug, newArticle(slug, author, i, "new")); err != nil {
+				return err
+			}
+			_, _, err := repos.Articles.Find(ctx, models.ArticleFilter{Tags: []string{"new"}}, models.Page{Limit: workers})
+			return err
+		})
+
+		checkTags(t, repos.Articles, map[string]int{"new": workers / 2})
+		checkSlugs(t, repos.Articles, models.ArticleFilter{Tags: []string{"new"}}, "a7", "a5", "a3", "a1")
+	})
+}
+
+// runConcurrently starts all calls at once and counts successful ones
+func runConcurrently(n int, fn func(i int) error) int {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		start = make(chan struct{})
+		ok    = 0
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+
+			if fn(i) == nil {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			}
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+
+	return ok
+}
+
+func at(minutes int) time.Time {
+	return base.Add(time.Duration(minutes) * time.Minute)
+}
+
+func newUser(username string) models.User {
+	return models.User{
+		Email:          username + "@example.com",
+		Username:       username,
+		HashedPassword: "hash",
+		CreatedAt:      base,
+		UpdatedAt:      base,
+	}
+}
+
+func newSession(id string, userId int64, familyId string) models.Session {
+	return models.Session{
+		ID:         id,
+		UserId:     userId,
+		FamilyId:   familyId,
+		TokenHash:  []byte("hash of " + id),
+		CreatedAt:  base,
+		LastSeenAt: base,
+		UserAgent:  "test",
+		IP:         "127.0.0.1",
+	}
+}
+
+func newRefreshToken(id, sessionId string, userId int64, familyId string) models.RefreshToken {
+	return models.RefreshToken{
+		ID:        id,
+		FamilyId:  familyId,
+		SessionId: sessionId,
+		UserId:    userId,
+		TokenHash: []byte("hash of " + id),
+		CreatedAt: base,
+		ExpiresAt: base.Add(time.Hour),
+	}
+}
+
+func newArticle(slug string, author models.Profile, minutes int, tags ...string) models.Article {
+	return models.Article{
+		Author:      author,
+		Slug:        slug,
+		Title:       "title of " + slug,
+		Description: "description of " + slug,
+		Body:        "body of " + slug,
+		TagList:     tags,
+		CreatedAt:   at(minutes),
+		UpdatedAt:   at(minutes),
+	}
+}
+
+func newComment(slug string, author models.Profile, minutes int) models.Comment {
+	return models.Comment{
+		ArticleSlug: slug,
+		Author:      author,
+		Body:        fmt.Sprintf("comment on %s at %d", slug, minutes),
+		CreatedAt:   at(minutes),
+		UpdatedAt:   at(minutes),
+	}
+}
+
+func mustSaveUser(t *testing.T, repo services.UserRepository, u models.User) int64 {
+	t.Helper()
+	ctx := context.Background()
+
+	id, err := repo.Save(ctx, u)
+	if err != nil {
+		t.Fatalf("cannot save user %s: %v", u.Username, err)
+	}
+
+	return id
+}
+
+func mustSaveProfile(t *testing.T, repo services.UserRepository, username string) models.Profile {
+	t.Helper()
+
+	u := newUser(username)
+	u.ID = mustSaveUser(t, repo, u)
+
+	return u.ToProfile()
+}
+
+func mustSaveSession(t *testing.T, repo services.SessionRepository, s models.Session) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := repo.Save(ctx, s); err != nil {
+		t.Fatalf("cannot save session %s: %v", s.ID, err)
+	}
+}
+
+func mustSaveRefreshToken(t *testing.T, repo services.SessionRepository, rt models.RefreshToken) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := repo.SaveRefreshToken(ctx, rt); err != nil {
+		t.Fatalf("cannot save refresh token %s: %v", rt.ID, err)
+	}
+}
+
+func mustUseRefreshToken(t *testing.T, repo services.SessionRepository, id string) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := repo.UseRefreshToken(ctx, id); err != nil {
+		t.Fatalf("cannot use refresh token %s: %v", id, err)
+	}
+}
+
+func mustSaveArticle(t *testing.T, repo services.ArticleRepository, a models.Article) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := repo.Save(ctx, a); err != nil {
+		t.Fatalf("cannot save article %s: %v", a.Slug, err)
+	}
+}
+
+func mustSaveComment(t *testing.T, repo services.CommentRepository, c models.Comment) models.Comment {
+	t.Helper()
+	ctx := context.Background()
+
+	id, err := repo.Save(ctx, c)
+	if err != nil {
+		t.Fatalf("cannot save comment on %s: %v", c.ArticleSlug, err)
+	}
+	c.ID = id
+
+	return c
+}
+
+func checkUser(t *testing.T, name string, want, have models.User) {
+	t.Helper()
+
+	if have.ID != want.ID || have.Username != want.Username || have.Email != want.Email ||
+		have.Bio != want.Bio || have.Image != want.Image || have.HashedPassword != want.HashedPassword ||
+		have.Verified != want.Verified || !have.CreatedAt.Equal(want.CreatedAt) || !have.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("%s: want %+v, have %+v", name, want, have)
+	}
+}
+
+func checkTaken(t *testing.T, field string, err error) {
+	t.Helper()
+
+	var errs models.ValidationErrors
+	if !errors.As(err, &errs) || len(errs[field]) == 0 {
+		t.Errorf("want %s taken, have: %v", field, err)
+	}
+}
+
+func checkSession(t *testing.T, want, have models.Session) {
+	t.Helper()
+
+	if have.ID != want.ID || have.UserId != want.UserId || have.FamilyId != want.FamilyId ||
+		string(have.TokenHash) != string(want.TokenHash) || have.UserAgent != want.UserAgent || have.IP != want.IP ||
+		!have.CreatedAt.Equal(want.CreatedAt) || !have.LastSeenAt.Equal(want.LastSeenAt) {
+		t.Errorf("session: want %+v, have %+v", want, have)
+	}
+}
+
+// checkNoSessions accepts ErrNotFound, it is how empty list is reported by some backends
+func checkNoSessions(t *testing.T, repo services.SessionRepository, userId int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	sessions, err := repo.GetAllByUser(ctx, userId)
+	if err != nil && !errors.Is(err, models.ErrNotFound) || len(sessions) != 0 {
+		t.Errorf("GetAllByUser: want no sessions, have: %v, %v", sessions, err)
+	}
+}
+
+func checkComment(t *testing.T, want, have models.Comment) {
+	t.Helper()
+
+	if have.ID != want.ID || have.ArticleSlug != want.ArticleSlug || have.Author.ID != want.Author.ID ||
+		have.Body != want.Body || !have.CreatedAt.Equal(want.CreatedAt) || !have.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("comment mismatch\nwant: %+v\nhave: %+v", want, have)
+	}
+}
+
+func checkComments(t *testing.T, repo services.CommentRepository, slug string, want ...models.Comment) {
+	t.Helper()
+
+	have, err := repo.GetAllByArticle(context.Background(), slug)
+	if err != nil {
+		t.Fatalf("GetAllByArticle %s: unexpected error: %v", slug, err)
+	}
+	if len(have) != len(want) {
+		t.Fatalf("GetAllByArticle %s: want %d comments, have %d", slug, len(want), len(have))
+	}
+	for i := range want {
+		checkComment(t, want[i], *have[i])
+	}
+}
+
+func checkFollowees(t *testing.T, repo services.FollowRepository, followerId int64, want ...int64) {
+	t.Helper()
+
+	have, err := repo.GetFollowees(context.Background(), followerId)
+	if err != nil {
+		t.Fatalf("GetFollowees: unexpected error: %v", err)
+	}
+
+	slices.Sort(have)
+	slices.Sort(want)
+	if !slices.Equal(have, want) {
+		t.Errorf("GetFollowees of %d: want %v, have %v", followerId, want, have)
+	}
+}
+
+func checkArticle(t *testing.T, want, have models.Article) {
+	t.Helper()
+
+	if have.Slug != want.Slug || have.Author.ID != want.Author.ID || have.Title != want.Title ||
+		have.Description != want.Description || have.Body != want.Body || !slices.Equal(have.TagList, want.TagList) ||
+		!have.CreatedAt.Equal(want.CreatedAt) || !have.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("article: want %+v, have %+v", want, have)
+	}
+}
+
+func checkTags(t *testing.T, repo services.ArticleRepository, want map[string]int) {
+	t.Helper()
+	ctx := context.Background()
+
+	have, err := repo.Tags(ctx)
+	if err != nil {
+		t.Fatalf("Tags: unexpected error: %v", err)
+	}
+
+	if len(have) != len(want) {
+		t.Errorf("Tags: want %v, have %v", want, have)
+		return
+	}
+	for tag, count := range want {
+		if have[tag] != count {
+			t.Errorf("Tags: want %v, have %v", want, have)
+			return
+		}
+	}
+}
+
+func checkSlugs(t *testing.T, repo services.ArticleRepository, filter models.ArticleFilter, want ...string) {
+	t.Helper()
+	ctx := context.Background()
+
+	articles, total, err := repo.Find(ctx, filter, models.Page{Limit: 100})
+	if err != nil {
+		t.Fatalf("Find %+v: unexpected error: %v", filter, err)
+	}
+
+	if have := slugsOf(articles); total != len(want) || !slices.Equal(have, want) {
+		t.Errorf("Find %+v: want %v, have %v of %d", filter, want, have, total)
+	}
+}
+
+func slugsOf(articles []*models.Article) []string {
+	slugs := make([]string, 0, len(articles))
+	for _, a := range articles {
+		slugs = append(slugs, a.Slug)
+	}
+
+	return slugs
+}
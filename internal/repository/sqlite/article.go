@@ -0,0 +1,164 @@
+package sqlite
+
+import (
+	"context"
+	"rwa/internal/models"
+	"rwa/internal/repository/sqlrepo"
+	"rwa/pkg/fulltext"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// scoresBatch is number of relevance scores inserted by one query
+const scoresBatch = 100
+
+// ArticleRepository uses the same in-memory search index as ram repository, it is built from table on start.
+// The rest is common for SQL databases
+type ArticleRepository struct {
+	*sqlrepo.ArticleRepository
+	db          *sqlx.DB
+	searchIndex *fulltext.Index
+
+	mu *sync.RWMutex
+}
+
+func NewArticleRepository(db *sqlx.DB) (*ArticleRepository, error) {
+	r := &ArticleRepository{
+		ArticleRepository: sqlrepo.NewArticleRepository(db, dialect),
+		db:                db,
+		searchIndex:       fulltext.NewIndex(),
+		mu:                &sync.RWMutex{},
+	}
+
+	rows := []models.Article{}
+	err := db.Select(&rows, "SELECT slug, title, description, body FROM articles")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range rows {
+		r.indexArticle(a)
+	}
+
+	return r, nil
+}
+
+// Find translates filter into single query. Relevance scores of found articles are loaded
+// into temporary table, there can be more of them than parameters allowed in one query
+func (r *ArticleRepository) Find(ctx context.Context, filter models.ArticleFilter, page models.Page) ([]*models.Article, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	q := r.Query(filter)
+
+	query := fulltext.ParseQuery(filter.Query)
+	if len(query) == 0 {
+		return r.Select(ctx, r.db, q, page)
+	}
+
+	scores := r.searchIndex.Search(query)
+	if len(scores) == 0 {
+		return []*models.Article{}, 0, nil
+	}
+
+	q.Score = "s.score"
+	q.JoinFirst("JOIN temp.search_scores s ON s.slug = a.slug")
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	// rollback drops temporary table too
+	defer tx.Rollback()
+
+	if err := loadScores(ctx, tx, scores); err != nil {
+		return nil, 0, err
+	}
+
+	return r.Select(ctx, tx, q, page)
+}
+
+func (r *ArticleRepository) Save(ctx context.Context, article models.Article) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.ArticleRepository.Save(ctx, article); err != nil {
+		return err
+	}
+
+	r.indexArticle(article)
+
+	return nil
+}
+
+func (r *ArticleRepository) Delete(ctx context.Context, article models.Article) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.ArticleRepository.Delete(ctx, article); err != nil {
+		return err
+	}
+
+	r.searchIndex.Remove(article.Slug)
+
+	return nil
+}
+
+func (r *ArticleRepository) Update(ctx context.Context, oldSlug string, article models.Article) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.ArticleRepository.Update(ctx, oldSlug, article); err != nil {
+		return err
+	}
+
+	r.searchIndex.Remove(oldSlug)
+	r.indexArticle(article)
+
+	return nil
+}
+
+// indexArticle replaces article in search index, title matches are the most valuable
+func (r *ArticleRepository) indexArticle(article models.Article) {
+	r.searchIndex.Add(article.Slug,
+		fulltext.Field{Text: article.Title, Weight: 3},
+		fulltext.Field{Text: article.Description, Weight: 2},
+		fulltext.Field{Text: article.Body, Weight: 1},
+	)
+}
+
+// loadScores creates temporary table of relevance scores, it is filled by batches
+// which keep number of parameters far below sqlite limit
+func loadScores(ctx context.Context, tx *sqlx.Tx, scores map[string]float64) error {
+	_, err := tx.ExecContext(ctx, "CREATE TEMP TABLE search_scores (slug TEXT PRIMARY KEY, score REAL NOT NULL)")
+	if err != nil {
+		return err
+	}
+
+	args := make([]interface{}, 0, 2*scoresBatch)
+	insert := func() error {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO temp.search_scores (slug, score) VALUES "+
+				strings.TrimSuffix(strings.Repeat("(?, ?), ", len(args)/2), ", "),
+			args...,
+		)
+		args = args[:0]
+		return err
+	}
+
+	for slug, score := range scores {
+		args = append(args, slug, score)
+		if len(args) == 2*scoresBatch {
+			if err := insert(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(args) != 0 {
+		return insert()
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package sqlite_test
+
+import (
+	"rwa/internal/repository/repotest"
+	"rwa/internal/repository/sqlite"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		db := openDB(t, ":memory:")
+
+		articleRepo, err := sqlite.NewArticleRepository(db)
+		if err != nil {
+			t.Fatalf("cannot create article repository: %v", err)
+		}
+
+		return repotest.Repositories{
+			Users:    sqlite.NewUserRepository(db),
+			Sessions: sqlite.NewSessionRepository(db),
+			Articles: articleRepo,
+			Comments: sqlite.NewCommentRepository(db),
+			Follows:  sqlite.NewFollowRepository(db),
+		}
+	})
+}
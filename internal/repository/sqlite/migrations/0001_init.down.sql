@@ -0,0 +1,7 @@
+DROP TABLE favorites;
+DROP TABLE article_tags;
+DROP TABLE tags;
+DROP TABLE articles;
+DROP TABLE refresh_tokens;
+DROP TABLE sessions;
+DROP TABLE users;
@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"rwa/internal/repository/sqlrepo"
+	"rwa/pkg/migrate"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// timeLayout is fixed width UTC, so stored times are compared as strings
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// sqlite runs the only writer, so rows don't need locks
+var dialect = sqlrepo.Dialect{
+	Time: func(t time.Time) interface{} {
+		return timestamp(t)
+	},
+	ScanTime: func(src interface{}) (time.Time, error) {
+		var t timestamp
+		err := t.Scan(src)
+
+		return t.Time(), err
+	},
+	InsertIgnore: "INSERT OR IGNORE",
+	DuplicateSlug: func(err error) bool {
+		return isDuplicate(err, "articles.slug")
+	},
+}
+
+// Open opens database file, ":memory:" is for tests, tables are created by migrations.
+// The only connection serializes writers, sqlite doesn't run them concurrently anyway
+func Open(path string) (*sqlx.DB, error) {
+	db, err := sqlx.Open("sqlite3", "file:"+path+"?_foreign_keys=1&_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// NewMigrator manages schema of repositories of the package
+func NewMigrator(db *sqlx.DB) (*migrate.Migrator, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(db.DB, fsys, migrate.SQLiteLock{})
+}
+
+// timestamp stores time in timeLayout
+type timestamp time.Time
+
+func (t timestamp) Value() (driver.Value, error) {
+	return time.Time(t).UTC().Format(timeLayout), nil
+}
+
+func (t *timestamp) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into timestamp", src)
+	}
+
+	parsed, err := time.Parse(timeLayout, s)
+	if err != nil {
+		return err
+	}
+
+	*t = timestamp(parsed)
+
+	return nil
+}
+
+func (t timestamp) Time() time.Time {
+	return time.Time(t)
+}
+
+// isDuplicate reports unique index violation, key is "<table>.<column>"
+func isDuplicate(err error, key string) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) &&
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique &&
+		strings.Contains(sqliteErr.Error(), key)
+}
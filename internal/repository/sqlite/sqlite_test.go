@@ -0,0 +1,266 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rwa/internal/models"
+	"rwa/internal/repository/sqlite"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// openDB opens database with applied migrations, it is closed at the end of test
+func openDB(t *testing.T, path string) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatalf("cannot open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := sqlite.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("cannot load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("cannot migrate: %v", err)
+	}
+
+	return db
+}
+
+func TestArticlesSurviveReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rwa.db")
+	created := time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.UTC)
+
+	db := openDB(t, path)
+
+	userId, err := sqlite.NewUserRepository(db).Save(ctx, models.User{Email: "golang@example.com", Username: "golang"})
+	if err != nil {
+		t.Fatalf("cannot save user: %v", err)
+	}
+
+	articleRepo, err := sqlite.NewArticleRepository(db)
+	if err != nil {
+		t.Fatalf("cannot create repository: %v", err)
+	}
+
+	err = articleRepo.Save(ctx, models.Article{
+		Author:    models.Profile{ID: userId},
+		Slug:      "gophers",
+		Title:     "Gophers",
+		Body:      "about gophers",
+		TagList:   []string{"go", "animals"},
+		CreatedAt: created,
+		UpdatedAt: created,
+	})
+	if err != nil {
+		t.Fatalf("cannot save article: %v", err)
+	}
+	db.Close()
+
+	// search index is built again from stored articles, applied migrations are skipped
+	db = openDB(t, path)
+
+	articleRepo, err = sqlite.NewArticleRepository(db)
+	if err != nil {
+		t.Fatalf("cannot create repository: %v", err)
+	}
+
+	articles, total, err := articleRepo.Find(ctx, models.ArticleFilter{Query: "gopher*"}, models.Page{Limit: 10})
+	if err != nil || total != 1 {
+		t.Fatalf("want one found article, have: %d, %v", total, err)
+	}
+
+	a := articles[0]
+	if a.Author.Username != "golang" || !a.CreatedAt.Equal(created) || len(a.TagList) != 2 || a.TagList[0] != "go" {
+		t.Fatalf("wrong article: %+v", a)
+	}
+}
+
+func TestFindManySearchHits(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t, ":memory:")
+
+	// the only connection gets lower limit, so hits are more than parameters allowed
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("cannot get connection: %v", err)
+	}
+	err = conn.Raw(func(c interface{}) error {
+		c.(*sqlite3.SQLiteConn).SetLimit(sqlite3.SQLITE_LIMIT_VARIABLE_NUMBER, 300)
+		return nil
+	})
+	conn.Close()
+	if err != nil {
+		t.Fatalf("cannot set limit: %v", err)
+	}
+
+	userId, err := sqlite.NewUserRepository(db).Save(ctx, models.User{Email: "golang@example.com", Username: "golang"})
+	if err != nil {
+		t.Fatalf("cannot save user: %v", err)
+	}
+
+	articleRepo, err := sqlite.NewArticleRepository(db)
+	if err != nil {
+		t.Fatalf("cannot create repository: %v", err)
+	}
+
+	for i := 0; i < 200; i++ {
+		err := articleRepo.Save(ctx, models.Article{
+			Author:  models.Profile{ID: userId},
+			Slug:    fmt.Sprintf("gophers-%d", i),
+			Title:   "Gophers",
+			TagList: []string{},
+		})
+		if err != nil {
+			t.Fatalf("cannot save article: %v", err)
+		}
+	}
+
+	articles, total, err := articleRepo.Find(ctx, models.ArticleFilter{Query: "gophers"}, models.Page{Limit: 10})
+	if err != nil || total != 200 || len(articles) != 10 {
+		t.Fatalf("want 10 of 200 found articles, have: %d of %d, %v", len(articles), total, err)
+	}
+
+	// scores don't outlive search
+	articles, total, err = articleRepo.Find(ctx, models.ArticleFilter{Query: "gophers"}, models.Page{Limit: 10, Offset: 195})
+	if err != nil || total != 200 || len(articles) != 5 {
+		t.Fatalf("want 5 of 200 found articles, have: %d of %d, %v", len(articles), total, err)
+	}
+}
+
+func TestCommentsAndFollowsSurviveReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rwa.db")
+
+	db := openDB(t, path)
+
+	userRepo := sqlite.NewUserRepository(db)
+	authorId, err := userRepo.Save(ctx, models.User{Email: "golang@example.com", Username: "golang"})
+	if err != nil {
+		t.Fatalf("cannot save user: %v", err)
+	}
+	readerId, err := userRepo.Save(ctx, models.User{Email: "reader@example.com", Username: "reader"})
+	if err != nil {
+		t.Fatalf("cannot save user: %v", err)
+	}
+
+	articleRepo, err := sqlite.NewArticleRepository(db)
+	if err != nil {
+		t.Fatalf("cannot create repository: %v", err)
+	}
+	if err := articleRepo.Save(ctx, models.Article{Author: models.Profile{ID: authorId}, Slug: "gophers"}); err != nil {
+		t.Fatalf("cannot save article: %v", err)
+	}
+
+	commentId, err := sqlite.NewCommentRepository(db).Save(ctx, models.Comment{
+		ArticleSlug: "gophers",
+		Author:      models.Profile{ID: readerId},
+		Body:        "nice",
+	})
+	if err != nil {
+		t.Fatalf("cannot save comment: %v", err)
+	}
+	if err := sqlite.NewFollowRepository(db).Follow(ctx, readerId, authorId); err != nil {
+		t.Fatalf("cannot follow: %v", err)
+	}
+	db.Close()
+
+	db = openDB(t, path)
+
+	comments, err := sqlite.NewCommentRepository(db).GetAllByArticle(ctx, "gophers")
+	if err != nil || len(comments) != 1 {
+		t.Fatalf("want one comment, have: %v, %v", comments, err)
+	}
+	if c := comments[0]; c.ID != commentId || c.Author.Username != "reader" || c.Body != "nice" {
+		t.Fatalf("wrong comment: %+v", c)
+	}
+
+	following, err := sqlite.NewFollowRepository(db).IsFollowing(ctx, readerId, authorId)
+	if err != nil || !following {
+		t.Fatalf("follow is lost, have: %v, %v", following, err)
+	}
+}
+
+func TestUserRepositoryDuplicateEmail(t *testing.T) {
+	ctx := context.Background()
+	repo := sqlite.NewUserRepository(openDB(t, ":memory:"))
+	if _, err := repo.Save(ctx, models.User{Email: "golang@example.com", Username: "golang"}); err != nil {
+		t.Fatalf("cannot save user: %v", err)
+	}
+
+	_, err := repo.Save(ctx, models.User{Email: "GOLANG@example.com", Username: "other"})
+
+	var errs models.ValidationErrors
+	if !errors.As(err, &errs) || len(errs["email"]) != 1 || len(errs["username"]) != 0 {
+		t.Fatalf("want email taken, have: %v", err)
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	repo := sqlite.NewUserRepository(openDB(t, ":memory:"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := repo.Save(ctx, models.User{Email: "golang@example.com", Username: "golang"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, have: %v", err)
+	}
+
+	if _, err := repo.GetByUsername(context.Background(), "golang"); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("canceled save must not store user, have: %v", err)
+	}
+}
+
+// TestMigrateUnversionedDatabase upgrades database created by schema file
+// which was applied on open before migrations, it has no schema_migrations table
+func TestMigrateUnversionedDatabase(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rwa.db")
+
+	schema, err := os.ReadFile(filepath.Join("testdata", "unversioned_schema.sql"))
+	if err != nil {
+		t.Fatalf("cannot read schema: %v", err)
+	}
+
+	old, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatalf("cannot open db: %v", err)
+	}
+	if _, err := old.Exec(string(schema)); err != nil {
+		t.Fatalf("cannot apply schema: %v", err)
+	}
+	if _, err := sqlite.NewUserRepository(old).Save(ctx, models.User{Email: "golang@example.com", Username: "golang"}); err != nil {
+		t.Fatalf("cannot save user: %v", err)
+	}
+	old.Close()
+
+	db := openDB(t, path)
+
+	if _, err := sqlite.NewUserRepository(db).GetByUsername(ctx, "golang"); err != nil {
+		t.Fatalf("user is lost after migration: %v", err)
+	}
+
+	migrator, err := sqlite.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("cannot load migrations: %v", err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("cannot get status: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			t.Fatalf("migration %d_%s is not applied", s.Version, s.Name)
+		}
+	}
+}
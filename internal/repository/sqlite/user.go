@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"context"
+	"rwa/internal/models"
+	"rwa/internal/repository/sqlrepo"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const userColumns = "id, email, username, bio, image, password_hash, verified, created_at, updated_at"
+
+type userRow struct {
+	ID           int64     `db:"id"`
+	Email        string    `db:"email"`
+	Username     string    `db:"username"`
+	Bio          string    `db:"bio"`
+	Image        string    `db:"image"`
+	PasswordHash string    `db:"password_hash"`
+	Verified     bool      `db:"verified"`
+	CreatedAt    timestamp `db:"created_at"`
+	UpdatedAt    timestamp `db:"updated_at"`
+}
+
+func (r userRow) toModel() *models.User {
+	return &models.User{
+		ID:             r.ID,
+		Email:          r.Email,
+		Username:       r.Username,
+		Bio:            r.Bio,
+		Image:          r.Image,
+		HashedPassword: r.PasswordHash,
+		Verified:       r.Verified,
+		CreatedAt:      r.CreatedAt.Time(),
+		UpdatedAt:      r.UpdatedAt.Time(),
+	}
+}
+
+// UserRepository keeps normalized username and email in key columns,
+// unique indexes on them make lookups case insensitive
+type UserRepository struct {
+	db *sqlx.DB
+}
+
+func NewUserRepository(db *sqlx.DB) *UserRepository {
+	return &UserRepository{
+		db: db,
+	}
+}
+
+func (r *UserRepository) GetById(ctx context.Context, id int64) (*models.User, error) {
+	return r.get(ctx, "id = ?", id)
+}
+
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.get(ctx, "username_key = ?", models.NormalizeIdentity(username))
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.get(ctx, "email_key = ?", models.NormalizeIdentity(email))
+}
+
+func (r *UserRepository) Save(ctx context.Context, user models.User) (int64, error) {
+	if err := r.checkTaken(ctx, user); err != nil {
+		return 0, err
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO users (email, email_key, username, username_key, bio, image, password_hash, verified, created_at, updated_at) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		user.Email, models.NormalizeIdentity(user.Email),
+		user.Username, models.NormalizeIdentity(user.Username),
+		user.Bio, user.Image, user.HashedPassword, user.Verified, timestamp(user.CreatedAt), timestamp(user.UpdatedAt),
+	)
+	if err != nil {
+		return 0, duplicateUser(err)
+	}
+
+	return res.LastInsertId()
+}
+
+func (r *UserRepository) Update(ctx context.Context, user models.User) error {
+	if _, err := r.GetById(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := r.checkTaken(ctx, user); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET email = ?, email_key = ?, username = ?, username_key = ?, "+
+			"bio = ?, image = ?, password_hash = ?, verified = ?, created_at = ?, updated_at = ? WHERE id = ?",
+		user.Email, models.NormalizeIdentity(user.Email),
+		user.Username, models.NormalizeIdentity(user.Username),
+		user.Bio, user.Image, user.HashedPassword, user.Verified, timestamp(user.CreatedAt), timestamp(user.UpdatedAt),
+		user.ID,
+	)
+
+	return duplicateUser(err)
+}
+
+func (r *UserRepository) ReplacePasswordHash(ctx context.Context, userId int64, oldHash, newHash string) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE users SET password_hash = ? WHERE id = ? AND password_hash = ?",
+		newHash, userId, oldHash,
+	)
+	if err != nil {
+		return err
+	}
+
+	return sqlrepo.CheckAffected(res)
+}
+
+func (r *UserRepository) Delete(ctx context.Context, user models.User) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", user.ID)
+	if err != nil {
+		return err
+	}
+
+	return sqlrepo.CheckAffected(res)
+}
+
+func (r *UserRepository) get(ctx context.Context, cond string, arg interface{}) (*models.User, error) {
+	row := userRow{}
+
+	err := r.db.GetContext(ctx, &row, "SELECT "+userColumns+" FROM users WHERE "+cond, arg)
+	if err != nil {
+		return nil, sqlrepo.NotFound(err)
+	}
+
+	return row.toModel(), nil
+}
+
+// checkTaken reports both taken fields at once, unique indexes still guard against races
+func (r *UserRepository) checkTaken(ctx context.Context, user models.User) error {
+	rows := []struct {
+		ID          int64  `db:"id"`
+		EmailKey    string `db:"email_key"`
+		UsernameKey string `db:"username_key"`
+	}{}
+
+	emailKey := models.NormalizeIdentity(user.Email)
+	usernameKey := models.NormalizeIdentity(user.Username)
+
+	err := r.db.SelectContext(ctx, &rows,
+		"SELECT id, email_key, username_key FROM users WHERE (username_key = ? OR email_key = ?) AND id <> ?",
+		usernameKey, emailKey, user.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	errs := models.ValidationErrors{}
+	for _, row := range rows {
+		if row.UsernameKey == usernameKey {
+			errs.Add("username", "has already been taken")
+		}
+		if row.EmailKey == emailKey {
+			errs.Add("email", "has already been taken")
+		}
+	}
+
+	return errs.Err()
+}
+
+func duplicateUser(err error) error {
+	switch {
+	case isDuplicate(err, "users.username_key"):
+		return models.ValidationErrors{"username": {"has already been taken"}}
+	case isDuplicate(err, "users.email_key"):
+		return models.ValidationErrors{"email": {"has already been taken"}}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,429 @@
+package sqlrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rwa/internal/models"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const articleColumns = "a.id, a.slug, a.title, a.description, a.body, a.created_at, a.updated_at, " +
+	"u.id AS author_id, u.username AS author_username, u.bio AS author_bio, u.image AS author_image"
+
+type articleRow struct {
+	ID             int64   `db:"id"`
+	Slug           string  `db:"slug"`
+	Title          string  `db:"title"`
+	Description    string  `db:"description"`
+	Body           string  `db:"body"`
+	CreatedAt      rawTime `db:"created_at"`
+	UpdatedAt      rawTime `db:"updated_at"`
+	AuthorId       int64   `db:"author_id"`
+	AuthorUsername string  `db:"author_username"`
+	AuthorBio      string  `db:"author_bio"`
+	AuthorImage    string  `db:"author_image"`
+	Score          float64 `db:"score"`
+}
+
+func (r articleRow) toModel(d Dialect) (*models.Article, error) {
+	createdAt, err := d.ScanTime(r.CreatedAt.src)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedAt, err := d.ScanTime(r.UpdatedAt.src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Article{
+		Author: models.Profile{
+			ID:       r.AuthorId,
+			Username: r.AuthorUsername,
+			Bio:      r.AuthorBio,
+			Image:    r.AuthorImage,
+		},
+		Slug:        r.Slug,
+		Title:       r.Title,
+		Description: r.Description,
+		Body:        r.Body,
+		TagList:     []string{},
+		CreatedAt:   createdAt,
+		UpdatedAt:   updatedAt,
+	}, nil
+}
+
+// ArticleRepository stores tags in their own table, article_tags keeps order in which tags were given.
+// Full-text search is left to repositories of databases, they build it with Query and Select
+type ArticleRepository struct {
+	db      *sqlx.DB
+	dialect Dialect
+}
+
+func NewArticleRepository(db *sqlx.DB, dialect Dialect) *ArticleRepository {
+	return &ArticleRepository{
+		db:      db,
+		dialect: dialect,
+	}
+}
+
+func (r *ArticleRepository) GetBySlug(ctx context.Context, slug string) (*models.Article, error) {
+	row := articleRow{}
+
+	err := r.db.GetContext(ctx, &row,
+		"SELECT "+articleColumns+", 0 AS score FROM articles a JOIN users u ON u.id = a.author_id WHERE a.slug = ?",
+		slug,
+	)
+	if err != nil {
+		return nil, NotFound(err)
+	}
+
+	articles, err := r.withTags(ctx, r.db, []articleRow{row})
+	if err != nil {
+		return nil, err
+	}
+
+	return articles[0], nil
+}
+
+// GetAllByUsers returns page of users articles sorted from newest to oldest and total count of them
+func (r *ArticleRepository) GetAllByUsers(ctx context.Context, userIds []int64, page models.Page) ([]*models.Article, int, error) {
+	if len(userIds) == 0 {
+		return []*models.Article{}, 0, nil
+	}
+
+	q := &ArticleQuery{Score: "0"}
+	q.Cond(InClause("a.author_id", len(userIds)), Int64sToArgs(userIds)...)
+
+	return r.Select(ctx, r.db, q, page)
+}
+
+func (r *ArticleRepository) Tags(ctx context.Context) (map[string]int, error) {
+	rows := []struct {
+		Name  string `db:"name"`
+		Count int    `db:"articles_count"`
+	}{}
+
+	err := r.db.SelectContext(ctx, &rows,
+		"SELECT t.name, COUNT(*) AS articles_count FROM tags t JOIN article_tags at ON at.tag_id = t.id GROUP BY t.name",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]int, len(rows))
+	for _, row := range rows {
+		tags[row.Name] = row.Count
+	}
+
+	return tags, nil
+}
+
+func (r *ArticleRepository) Save(ctx context.Context, article models.Article) error {
+	return WithTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			"INSERT INTO articles (slug, author_id, title, description, body, created_at, updated_at) "+
+				"VALUES (?, ?, ?, ?, ?, ?, ?)",
+			article.Slug, article.Author.ID, article.Title, article.Description, article.Body,
+			r.dialect.Time(article.CreatedAt), r.dialect.Time(article.UpdatedAt),
+		)
+		if err != nil {
+			return r.duplicateSlug(err, article.Slug)
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return r.saveTags(ctx, tx, id, article.TagList)
+	})
+}
+
+// Delete removes article, its tags links and favorites are removed by foreign keys
+func (r *ArticleRepository) Delete(ctx context.Context, article models.Article) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM articles WHERE slug = ?", article.Slug)
+	if err != nil {
+		return err
+	}
+
+	return CheckAffected(res)
+}
+
+func (r *ArticleRepository) Update(ctx context.Context, oldSlug string, article models.Article) error {
+	return WithTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var id int64
+		err := tx.GetContext(ctx, &id, "SELECT id FROM articles WHERE slug = ?"+r.dialect.ForUpdate, oldSlug)
+		if err != nil {
+			return NotFound(err)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE articles SET slug = ?, author_id = ?, title = ?, description = ?, body = ?, "+
+				"created_at = ?, updated_at = ? WHERE id = ?",
+			article.Slug, article.Author.ID, article.Title, article.Description, article.Body,
+			r.dialect.Time(article.CreatedAt), r.dialect.Time(article.UpdatedAt), id,
+		)
+		if err != nil {
+			return r.duplicateSlug(err, article.Slug)
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM article_tags WHERE article_id = ?", id); err != nil {
+			return err
+		}
+
+		return r.saveTags(ctx, tx, id, article.TagList)
+	})
+}
+
+// Query translates every condition of filter except full-text search,
+// tag conditions are joins with article_tags
+func (r *ArticleRepository) Query(filter models.ArticleFilter) *ArticleQuery {
+	q := &ArticleQuery{Score: "0"}
+
+	if filter.AuthorId != 0 {
+		q.Cond("a.author_id = ?", filter.AuthorId)
+	}
+
+	if filter.FavoritedBy != 0 {
+		q.Join("JOIN favorites f ON f.article_id = a.id AND f.user_id = ?", filter.FavoritedBy)
+	}
+
+	if tags := uniqueTags(filter.Tags); len(tags) != 0 {
+		tagged := "SELECT DISTINCT at.article_id FROM article_tags at JOIN tags t ON t.id = at.tag_id " +
+			"WHERE " + InClause("t.name", len(tags))
+		args := StringsToArgs(tags)
+
+		// article has every tag when all of them are found in its links
+		if filter.TagMode == models.TagModeAll {
+			tagged = "SELECT at.article_id FROM article_tags at JOIN tags t ON t.id = at.tag_id " +
+				"WHERE " + InClause("t.name", len(tags)) + " GROUP BY at.article_id HAVING COUNT(*) = ?"
+			args = append(args, len(tags))
+		}
+
+		q.Join("JOIN ("+tagged+") tagged ON tagged.article_id = a.id", args...)
+	}
+
+	if tags := uniqueTags(filter.ExcludeTags); len(tags) != 0 {
+		q.Join(
+			"LEFT JOIN (SELECT DISTINCT at.article_id FROM article_tags at JOIN tags t ON t.id = at.tag_id "+
+				"WHERE "+InClause("t.name", len(tags))+") excluded ON excluded.article_id = a.id",
+			StringsToArgs(tags)...,
+		)
+		q.Cond("excluded.article_id IS NULL")
+	}
+
+	if !filter.CreatedFrom.IsZero() {
+		q.Cond("a.created_at >= ?", r.dialect.Time(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		q.Cond("a.created_at <= ?", r.dialect.Time(filter.CreatedTo))
+	}
+	if !filter.UpdatedFrom.IsZero() {
+		q.Cond("a.updated_at >= ?", r.dialect.Time(filter.UpdatedFrom))
+	}
+	if !filter.UpdatedTo.IsZero() {
+		q.Cond("a.updated_at <= ?", r.dialect.Time(filter.UpdatedTo))
+	}
+
+	return q
+}
+
+// Select returns page of articles sorted by relevance, then from newest to oldest,
+// total count doesn't depend on page. Query runs on db, so it can see tables of transaction
+func (r *ArticleRepository) Select(ctx context.Context, db sqlx.QueryerContext, q *ArticleQuery, page models.Page) ([]*models.Article, int, error) {
+	from, fromArgs := q.from()
+
+	var total int
+	if err := sqlx.GetContext(ctx, db, &total, "SELECT COUNT(*) "+from, fromArgs...); err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT " + articleColumns + ", " + q.Score + " AS score " + from
+	args := append(append([]interface{}{}, q.ScoreArgs...), fromArgs...)
+
+	offset := page.Offset
+	if page.After != nil {
+		offset = 0
+
+		keyset := "(a.created_at < ? OR (a.created_at = ? AND a.slug < ?))"
+		if len(q.where) == 0 {
+			query += " WHERE " + keyset
+		} else {
+			query += " AND " + keyset
+		}
+		after := r.dialect.Time(page.After.CreatedAt)
+		args = append(args, after, after, page.After.Slug)
+	}
+
+	query += " ORDER BY score DESC, a.created_at DESC, a.slug DESC LIMIT ? OFFSET ?"
+	args = append(args, page.Limit, offset)
+
+	rows := []articleRow{}
+	if err := sqlx.SelectContext(ctx, db, &rows, query, args...); err != nil {
+		return nil, 0, err
+	}
+
+	articles, err := r.withTags(ctx, db, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return articles, total, nil
+}
+
+// withTags converts rows to articles and loads their tags with one query
+func (r *ArticleRepository) withTags(ctx context.Context, db sqlx.QueryerContext, rows []articleRow) ([]*models.Article, error) {
+	articles := make([]*models.Article, 0, len(rows))
+	if len(rows) == 0 {
+		return articles, nil
+	}
+
+	byId := make(map[int64]*models.Article, len(rows))
+	ids := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		a, err := row.toModel(r.dialect)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+		byId[row.ID] = a
+		ids = append(ids, row.ID)
+	}
+
+	tags := []struct {
+		ArticleId int64  `db:"article_id"`
+		Name      string `db:"name"`
+	}{}
+
+	err := sqlx.SelectContext(ctx, db, &tags,
+		"SELECT at.article_id, t.name FROM article_tags at JOIN tags t ON t.id = at.tag_id "+
+			"WHERE "+InClause("at.article_id", len(ids))+" ORDER BY at.article_id, at.position",
+		Int64sToArgs(ids)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tags {
+		a := byId[t.ArticleId]
+		a.TagList = append(a.TagList, t.Name)
+	}
+
+	return articles, nil
+}
+
+// saveTags creates missing tags and links them to article in given order
+func (r *ArticleRepository) saveTags(ctx context.Context, tx *sqlx.Tx, articleId int64, tagList []string) error {
+	tags := uniqueTags(tagList)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx,
+		r.dialect.InsertIgnore+" INTO tags (name) VALUES "+strings.TrimSuffix(strings.Repeat("(?), ", len(tags)), ", "),
+		StringsToArgs(tags)...,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows := []struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}{}
+	err = tx.SelectContext(ctx, &rows, "SELECT id, name FROM tags WHERE "+InClause("name", len(tags)), StringsToArgs(tags)...)
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		ids[row.Name] = row.ID
+	}
+
+	args := make([]interface{}, 0, 3*len(tags))
+	for pos, t := range tags {
+		id, exist := ids[t]
+		if !exist {
+			return fmt.Errorf("tag %q is not found after insert", t)
+		}
+		args = append(args, articleId, id, pos)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO article_tags (article_id, tag_id, position) VALUES "+
+			strings.TrimSuffix(strings.Repeat("(?, ?, ?), ", len(tags)), ", "),
+		args...,
+	)
+
+	return err
+}
+
+func (r *ArticleRepository) duplicateSlug(err error, slug string) error {
+	if r.dialect.DuplicateSlug(err) {
+		return errors.New("slug must be unique: " + slug)
+	}
+
+	return err
+}
+
+func uniqueTags(tagList []string) []string {
+	seen := make(map[string]bool, len(tagList))
+	tags := make([]string, 0, len(tagList))
+	for _, t := range tagList {
+		if !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+
+	return tags
+}
+
+// ArticleQuery collects joins and conditions of articles list,
+// arguments are kept in order of placeholders
+type ArticleQuery struct {
+	// Score is expression of relevance, ScoreArgs are its arguments
+	Score     string
+	ScoreArgs []interface{}
+
+	joins     []string
+	joinArgs  []interface{}
+	where     []string
+	whereArgs []interface{}
+}
+
+func (q *ArticleQuery) Join(clause string, args ...interface{}) {
+	q.joins = append(q.joins, clause)
+	q.joinArgs = append(q.joinArgs, args...)
+}
+
+// JoinFirst adds join which the others may refer to
+func (q *ArticleQuery) JoinFirst(clause string, args ...interface{}) {
+	q.joins = append([]string{clause}, q.joins...)
+	q.joinArgs = append(append([]interface{}{}, args...), q.joinArgs...)
+}
+
+func (q *ArticleQuery) Cond(clause string, args ...interface{}) {
+	q.where = append(q.where, clause)
+	q.whereArgs = append(q.whereArgs, args...)
+}
+
+// from builds FROM and WHERE parts of query with their arguments
+func (q *ArticleQuery) from() (string, []interface{}) {
+	from := "FROM articles a JOIN users u ON u.id = a.author_id"
+	for _, j := range q.joins {
+		from += " " + j
+	}
+	if len(q.where) != 0 {
+		from += " WHERE " + strings.Join(q.where, " AND ")
+	}
+
+	args := append(append([]interface{}{}, q.joinArgs...), q.whereArgs...)
+
+	return from, args
+}
@@ -0,0 +1,139 @@
+package sqlrepo
+
+import (
+	"context"
+	"rwa/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const commentColumns = "c.id, a.slug AS article_slug, c.body, c.created_at, c.updated_at, " +
+	"u.id AS author_id, u.username AS author_username, u.bio AS author_bio, u.image AS author_image"
+
+type commentRow struct {
+	ID             int64   `db:"id"`
+	ArticleSlug    string  `db:"article_slug"`
+	Body           string  `db:"body"`
+	CreatedAt      rawTime `db:"created_at"`
+	UpdatedAt      rawTime `db:"updated_at"`
+	AuthorId       int64   `db:"author_id"`
+	AuthorUsername string  `db:"author_username"`
+	AuthorBio      string  `db:"author_bio"`
+	AuthorImage    string  `db:"author_image"`
+}
+
+func (r commentRow) toModel(d Dialect) (*models.Comment, error) {
+	createdAt, err := d.ScanTime(r.CreatedAt.src)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedAt, err := d.ScanTime(r.UpdatedAt.src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Comment{
+		ID:          r.ID,
+		ArticleSlug: r.ArticleSlug,
+		Author: models.Profile{
+			ID:       r.AuthorId,
+			Username: r.AuthorUsername,
+			Bio:      r.AuthorBio,
+			Image:    r.AuthorImage,
+		},
+		Body:      r.Body,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+	}, nil
+}
+
+// CommentRepository references articles by id, so slug changes need no work
+type CommentRepository struct {
+	db      *sqlx.DB
+	dialect Dialect
+}
+
+func NewCommentRepository(db *sqlx.DB, dialect Dialect) *CommentRepository {
+	return &CommentRepository{
+		db:      db,
+		dialect: dialect,
+	}
+}
+
+func (r *CommentRepository) GetById(ctx context.Context, id int64) (*models.Comment, error) {
+	var row commentRow
+
+	err := r.db.GetContext(ctx, &row,
+		"SELECT "+commentColumns+" FROM comments c "+
+			"JOIN articles a ON a.id = c.article_id JOIN users u ON u.id = c.author_id WHERE c.id = ?",
+		id,
+	)
+	if err != nil {
+		return nil, NotFound(err)
+	}
+
+	return row.toModel(r.dialect)
+}
+
+func (r *CommentRepository) GetAllByArticle(ctx context.Context, slug string) ([]*models.Comment, error) {
+	var rows []commentRow
+
+	err := r.db.SelectContext(ctx, &rows,
+		"SELECT "+commentColumns+" FROM comments c "+
+			"JOIN articles a ON a.id = c.article_id JOIN users u ON u.id = c.author_id WHERE a.slug = ? ORDER BY c.id",
+		slug,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]*models.Comment, 0, len(rows))
+	for _, row := range rows {
+		c, err := row.toModel(r.dialect)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, nil
+}
+
+func (r *CommentRepository) Save(ctx context.Context, comment models.Comment) (int64, error) {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO comments (article_id, author_id, body, created_at, updated_at) "+
+			"SELECT id, ?, ?, ?, ? FROM articles WHERE slug = ?",
+		comment.Author.ID, comment.Body, r.dialect.Time(comment.CreatedAt), r.dialect.Time(comment.UpdatedAt), comment.ArticleSlug,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if err := CheckAffected(res); err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+func (r *CommentRepository) Delete(ctx context.Context, comment models.Comment) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM comments WHERE id = ?", comment.ID)
+	if err != nil {
+		return err
+	}
+
+	return CheckAffected(res)
+}
+
+func (r *CommentRepository) DeleteAllByArticle(ctx context.Context, slug string) error {
+	_, err := r.db.ExecContext(ctx,
+		"DELETE FROM comments WHERE article_id IN (SELECT id FROM articles WHERE slug = ?)",
+		slug,
+	)
+
+	return err
+}
+
+func (r *CommentRepository) UpdateSlug(ctx context.Context, oldSlug, newSlug string) error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package sqlrepo
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type FollowRepository struct {
+	db      *sqlx.DB
+	dialect Dialect
+}
+
+func NewFollowRepository(db *sqlx.DB, dialect Dialect) *FollowRepository {
+	return &FollowRepository{
+		db:      db,
+		dialect: dialect,
+	}
+}
+
+func (r *FollowRepository) Follow(ctx context.Context, followerId, followeeId int64) error {
+	_, err := r.db.ExecContext(ctx,
+		r.dialect.InsertIgnore+" INTO follows (follower_id, followee_id) VALUES (?, ?)",
+		followerId, followeeId,
+	)
+
+	return err
+}
+
+func (r *FollowRepository) Unfollow(ctx context.Context, followerId, followeeId int64) error {
+	_, err := r.db.ExecContext(ctx,
+		"DELETE FROM follows WHERE follower_id = ? AND followee_id = ?",
+		followerId, followeeId,
+	)
+
+	return err
+}
+
+func (r *FollowRepository) IsFollowing(ctx context.Context, followerId, followeeId int64) (bool, error) {
+	var following bool
+
+	err := r.db.GetContext(ctx, &following,
+		"SELECT EXISTS (SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = ?)",
+		followerId, followeeId,
+	)
+
+	return following, err
+}
+
+func (r *FollowRepository) GetFollowees(ctx context.Context, followerId int64) ([]int64, error) {
+	followees := []int64{}
+
+	err := r.db.SelectContext(ctx, &followees,
+		"SELECT followee_id FROM follows WHERE follower_id = ?",
+		followerId,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return followees, nil
+}
@@ -0,0 +1,106 @@
+// Package sqlrepo keeps queries which are the same for every SQL database,
+// repositories of sqlite and mysql packages are built on it and describe only their Dialect
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"rwa/internal/models"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect describes what differs between databases
+type Dialect struct {
+	// Time converts time into query argument
+	Time func(t time.Time) interface{}
+	// ScanTime converts value read from time column
+	ScanTime func(src interface{}) (time.Time, error)
+	// InsertIgnore starts insert which skips rows violating unique keys
+	InsertIgnore string
+	// ForUpdate locks selected rows till the end of transaction,
+	// it is empty where writers don't run concurrently
+	ForUpdate string
+	// DuplicateSlug reports violation of unique index of article slugs
+	DuplicateSlug func(err error) bool
+}
+
+func WithTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func NotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrNotFound
+	}
+
+	return err
+}
+
+// CheckAffected turns update or delete of missing row into ErrNotFound
+func CheckAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func Placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func Int64sToArgs(ids []int64) []interface{} {
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	return args
+}
+
+func StringsToArgs(ss []string) []interface{} {
+	args := make([]interface{}, 0, len(ss))
+	for _, s := range ss {
+		args = append(args, s)
+	}
+
+	return args
+}
+
+func InClause(column string, n int) string {
+	return fmt.Sprintf("%s IN (%s)", column, Placeholders(n))
+}
+
+// rawTime keeps value of time column until Dialect converts it
+type rawTime struct {
+	src interface{}
+}
+
+func (t *rawTime) Scan(src interface{}) error {
+	// driver reuses bytes after scan
+	if b, ok := src.([]byte); ok {
+		src = string(b)
+	}
+	t.src = src
+
+	return nil
+}
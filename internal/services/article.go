@@ -1,10 +1,12 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"regexp"
 	"rwa/internal/models"
+	"sort"
 	"strings"
 	"time"
 )
@@ -12,27 +14,43 @@ import (
 var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9 ]+`)
 
 type ArticleRepository interface {
-	GetBySlug(string) (*models.Article, error)
-	GetAllByUser(user models.User, tags []string) ([]*models.Article, error)
-	GetAll(tags []string) ([]*models.Article, error)
-	Save(models.Article) error
-	Delete(models.Article) error
-	Update(string, models.Article) error
+	GetBySlug(ctx context.Context, slug string) (*models.Article, error)
+	Find(ctx context.Context, filter models.ArticleFilter, page models.Page) ([]*models.Article, int, error)
+	GetAllByUsers(ctx context.Context, userIds []int64, page models.Page) ([]*models.Article, int, error)
+	Tags(ctx context.Context) (map[string]int, error)
+	Save(ctx context.Context, article models.Article) error
+	Delete(ctx context.Context, article models.Article) error
+	Update(ctx context.Context, oldSlug string, article models.Article) error
+}
+
+type FavoriteRepository interface {
+	Add(ctx context.Context, userId int64, slug string) error
+	Remove(ctx context.Context, userId int64, slug string) error
+	IsFavorited(ctx context.Context, userId int64, slug string) (bool, error)
+	Count(ctx context.Context, slug string) (int, error)
+	UpdateSlug(ctx context.Context, oldSlug, newSlug string) error
+	DeleteAllByArticle(ctx context.Context, slug string) error
 }
 
 type ArticleService struct {
-	articleRepo ArticleRepository
+	articleRepo  ArticleRepository
+	favoriteRepo FavoriteRepository
+	commentRepo  CommentRepository
+	userService  *UserService
 }
 
-func NewArticleService(articleRepo ArticleRepository) *ArticleService {
+func NewArticleService(articleRepo ArticleRepository, favoriteRepo FavoriteRepository, commentRepo CommentRepository, us *UserService) *ArticleService {
 	return &ArticleService{
-		articleRepo: articleRepo,
+		articleRepo:  articleRepo,
+		favoriteRepo: favoriteRepo,
+		commentRepo:  commentRepo,
+		userService:  us,
 	}
 }
 
-func (as *ArticleService) CreateArticle(user models.User, articleInfo models.ArticleInfo) (*models.Article, error) {
+func (as *ArticleService) CreateArticle(ctx context.Context, user models.User, articleInfo models.ArticleInfo) (*models.Article, error) {
 	if articleInfo.Slug != "" {
-		articleBySlug, _ := as.articleRepo.GetBySlug(articleInfo.Slug)
+		articleBySlug, _ := as.articleRepo.GetBySlug(ctx, articleInfo.Slug)
 		if articleBySlug != nil {
 			return nil, errors.New("slug must be unique")
 		}
@@ -42,19 +60,17 @@ func (as *ArticleService) CreateArticle(user models.User, articleInfo models.Art
 
 	createdAt := time.Now()
 	article := models.Article{
-		Author:         user,
-		Body:           articleInfo.Body,
-		Title:          articleInfo.Title,
-		Description:    articleInfo.Description,
-		Favorited:      false,
-		FavoritesCount: 0,
-		Slug:           articleInfo.Slug,
-		TagList:        articleInfo.TagList,
-		CreatedAt:      createdAt,
-		UpdatedAt:      createdAt,
+		Author:      user.ToProfile(),
+		Body:        articleInfo.Body,
+		Title:       articleInfo.Title,
+		Description: articleInfo.Description,
+		Slug:        articleInfo.Slug,
+		TagList:     articleInfo.TagList,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
 	}
 
-	err := as.articleRepo.Save(article)
+	err := as.articleRepo.Save(ctx, article)
 	if err != nil {
 		fmt.Println(err)
 
@@ -64,67 +80,211 @@ func (as *ArticleService) CreateArticle(user models.User, articleInfo models.Art
 	return &article, err
 }
 
-func (as *ArticleService) UpdateArticle(user models.User, article models.Article, articleInfo models.ArticleInfo) (*models.Article, error) {
+func (as *ArticleService) UpdateArticle(ctx context.Context, user models.User, article models.Article, articleInfo models.ArticleInfo) (*models.Article, error) {
 	if article.Author.ID != user.ID {
-		return nil, errors.New("error: not permission")
+		return nil, models.ErrPermissionDenied
 	}
 	oldSlug := article.Slug
 
 	if articleInfo.Slug != "" {
 		article.Slug = articleInfo.Slug
 	}
-
-	article.Title = articleInfo.Title
-	article.Body = articleInfo.Body
-	article.Description = articleInfo.Description
-	article.TagList = articleInfo.TagList
+	if articleInfo.Title != "" {
+		article.Title = articleInfo.Title
+	}
+	if articleInfo.Body != "" {
+		article.Body = articleInfo.Body
+	}
+	if articleInfo.Description != "" {
+		article.Description = articleInfo.Description
+	}
+	if articleInfo.TagList != nil {
+		article.TagList = articleInfo.TagList
+	}
 	article.UpdatedAt = time.Now()
 
-	err := as.articleRepo.Update(oldSlug, article)
+	err := as.articleRepo.Update(ctx, oldSlug, article)
 	if err != nil {
 		fmt.Println(err)
 
 		return nil, errors.New("error: cannot update article")
 	}
 
-	return &article, err
+	if oldSlug != article.Slug {
+		err = as.favoriteRepo.UpdateSlug(ctx, oldSlug, article.Slug)
+		if err != nil {
+			return nil, err
+		}
+
+		err = as.commentRepo.UpdateSlug(ctx, oldSlug, article.Slug)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = as.fill(ctx, &user, &article)
+	if err != nil {
+		return nil, err
+	}
+
+	return &article, nil
 }
 
-func (as *ArticleService) DeleteArticle(user models.User, article models.Article) error {
+func (as *ArticleService) DeleteArticle(ctx context.Context, user models.User, article models.Article) error {
 	if article.Author.ID != user.ID {
-		return errors.New("error: not permission")
+		return models.ErrPermissionDenied
 	}
 
-	err := as.articleRepo.Delete(article)
+	err := as.articleRepo.Delete(ctx, article)
 	if err != nil {
 		fmt.Println(err)
 
 		return errors.New("error: cannot delete article")
 	}
 
-	return nil
+	err = as.commentRepo.DeleteAllByArticle(ctx, article.Slug)
+	if err != nil {
+		return err
+	}
+
+	return as.favoriteRepo.DeleteAllByArticle(ctx, article.Slug)
+}
+
+func (as *ArticleService) Favorite(ctx context.Context, user models.User, article models.Article) (*models.Article, error) {
+	err := as.favoriteRepo.Add(ctx, user.ID, article.Slug)
+	if err != nil {
+		return nil, err
+	}
+
+	err = as.fill(ctx, &user, &article)
+	if err != nil {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+func (as *ArticleService) Unfavorite(ctx context.Context, user models.User, article models.Article) (*models.Article, error) {
+	err := as.favoriteRepo.Remove(ctx, user.ID, article.Slug)
+	if err != nil {
+		return nil, err
+	}
+
+	err = as.fill(ctx, &user, &article)
+	if err != nil {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+// viewer may be nil for anonymous requests, then favorited and following are always false.
+// Lists are sorted from newest to oldest, total count of found articles is returned with page
+func (as *ArticleService) GetArticles(ctx context.Context, viewer *models.User, filter models.ArticleFilter, page models.Page) ([]*models.Article, int, error) {
+	articles, total, err := as.articleRepo.Find(ctx, filter, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return articles, total, as.fill(ctx, viewer, articles...)
 }
 
-func (as *ArticleService) GetAll(tags []string) ([]*models.Article, error) {
-	return as.articleRepo.GetAll(tags)
+func (as *ArticleService) GetByTitle(ctx context.Context, viewer *models.User, title string) (*models.Article, error) {
+	article, err := as.articleRepo.GetBySlug(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+
+	return article, as.fill(ctx, viewer, article)
 }
 
-func (as *ArticleService) GetByTitle(title string) (*models.Article, error) {
-	article, err := as.articleRepo.GetBySlug(title)
+// GetFeed returns page of followed authors articles and total count of them
+func (as *ArticleService) GetFeed(ctx context.Context, user models.User, page models.Page) ([]*models.Article, int, error) {
+	followees, err := as.userService.GetFolloweesIds(ctx, user)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	articles, total, err := as.articleRepo.GetAllByUsers(ctx, followees, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return articles, total, as.fill(ctx, &user, articles...)
+}
+
+// GetTags returns tags in use sorted from most popular
+func (as *ArticleService) GetTags(ctx context.Context) ([]models.TagCount, error) {
+	tags, err := as.articleRepo.Tags(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return article, nil
+	counts := make([]models.TagCount, 0, len(tags))
+	for t, c := range tags {
+		counts = append(counts, models.TagCount{Tag: t, ArticlesCount: c})
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].ArticlesCount != counts[j].ArticlesCount {
+			return counts[i].ArticlesCount > counts[j].ArticlesCount
+		}
+		return counts[i].Tag < counts[j].Tag
+	})
+
+	return counts, nil
+}
+
+func (as *ArticleService) fill(ctx context.Context, viewer *models.User, articles ...*models.Article) error {
+	for _, a := range articles {
+		err := as.fillFavorites(ctx, viewer, a)
+		if err != nil {
+			return err
+		}
+
+		err = as.fillAuthor(ctx, viewer, a)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (as *ArticleService) GetAllByUser(user models.User, tags []string) ([]*models.Article, error) {
-	articles, err := as.articleRepo.GetAllByUser(user, tags)
+func (as *ArticleService) fillFavorites(ctx context.Context, viewer *models.User, a *models.Article) error {
+	count, err := as.favoriteRepo.Count(ctx, a.Slug)
 	if err != nil {
-		return articles, err
+		return err
 	}
+	a.FavoritesCount = count
 
-	return articles, nil
+	a.Favorited = false
+	if viewer != nil {
+		a.Favorited, err = as.favoriteRepo.IsFavorited(ctx, viewer.ID, a.Slug)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (as *ArticleService) fillAuthor(ctx context.Context, viewer *models.User, a *models.Article) error {
+	author, err := as.userService.GetUserById(ctx, a.Author.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	profile, err := as.userService.GetProfile(ctx, viewer, *author)
+	if err != nil {
+		return err
+	}
+	a.Author = *profile
+
+	return nil
 }
 
 func (as *ArticleService) generateSlug(articleInfo models.ArticleInfo) string {
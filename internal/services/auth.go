@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"rwa/internal/models"
+	"rwa/pkg/clock"
+	"sync"
+	"time"
+)
+
+type LoginAttemptRepository interface {
+	Get(ctx context.Context, key string) (*models.LoginAttempts, error)
+	Save(ctx context.Context, key string, attempts models.LoginAttempts) error
+	Delete(ctx context.Context, key string) error
+	// DeleteExpired removes attempts which are not locked after lockedBefore
+	// and have no failures since failedBefore
+	DeleteExpired(ctx context.Context, lockedBefore, failedBefore time.Time) (int, error)
+}
+
+type LoginPolicy struct {
+	// MaxAccountFailures failed logins in a row lock the account for LockDuration
+	MaxAccountFailures int
+	// MaxIPFailures failed logins from one client lock it for LockDuration
+	MaxIPFailures int
+	// FailuresWindow resets counter if there were no failures for this time
+	FailuresWindow time.Duration
+	LockDuration   time.Duration
+}
+
+type AuthService struct {
+	userService  *UserService
+	attemptsRepo LoginAttemptRepository
+	clock        clock.Clock
+	policy       LoginPolicy
+
+	// dummyUser has real hash, it is checked for unknown emails
+	// to answer as long as for wrong passwords
+	dummyUser     models.User
+	dummyUserOnce *sync.Once
+	mu            *sync.Mutex
+}
+
+func NewAuthService(us *UserService, attemptsRepo LoginAttemptRepository, clk clock.Clock, policy LoginPolicy) *AuthService {
+	return &AuthService{
+		userService:   us,
+		attemptsRepo:  attemptsRepo,
+		clock:         clk,
+		policy:        policy,
+		dummyUserOnce: &sync.Once{},
+		mu:            &sync.Mutex{},
+	}
+}
+
+// Login checks credentials, unknown email and wrong password both give models.ErrInvalidCredentials
+func (as *AuthService) Login(ctx context.Context, email, password, clientIP string) (*models.User, error) {
+	// lockout counts failures of account, so key is built from the same form email is looked up in
+	email = normalizeEmail(email)
+	accountKey := "account:" + email
+	ipKey := "ip:" + clientIP
+
+	for _, key := range []string{accountKey, ipKey} {
+		locked, err := as.isLocked(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			return nil, models.ErrTooManyAttempts
+		}
+	}
+
+	user, err := as.userService.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+
+	if user == nil {
+		as.userService.VerificatePassword(ctx, as.getDummyUser(), password)
+	}
+
+	if user == nil || !as.userService.VerificatePassword(ctx, *user, password) {
+		err = as.registerFailure(ctx, accountKey, as.policy.MaxAccountFailures)
+		if err != nil {
+			return nil, err
+		}
+
+		err = as.registerFailure(ctx, ipKey, as.policy.MaxIPFailures)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, models.ErrInvalidCredentials
+	}
+
+	err = as.attemptsRepo.Delete(ctx, accountKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// DeleteExpired removes attempts which neither lock anything nor count failures any more
+func (as *AuthService) DeleteExpired(ctx context.Context) (int, error) {
+	now := as.clock.Now()
+
+	return as.attemptsRepo.DeleteExpired(ctx, now, now.Add(-as.policy.FailuresWindow))
+}
+
+func (as *AuthService) isLocked(ctx context.Context, key string) (bool, error) {
+	attempts, err := as.attemptsRepo.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return attempts.LockedUntil.After(as.clock.Now()), nil
+}
+
+func (as *AuthService) registerFailure(ctx context.Context, key string, maxFailures int) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	now := as.clock.Now()
+
+	attempts := models.LoginAttempts{}
+	stored, err := as.attemptsRepo.Get(ctx, key)
+	if err == nil {
+		attempts = *stored
+	} else if !errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+
+	if now.Sub(attempts.FirstFailureAt) > as.policy.FailuresWindow {
+		attempts = models.LoginAttempts{FirstFailureAt: now}
+	}
+
+	attempts.Failures++
+	if attempts.Failures >= maxFailures {
+		attempts = models.LoginAttempts{LockedUntil: now.Add(as.policy.LockDuration)}
+	}
+
+	return as.attemptsRepo.Save(ctx, key, attempts)
+}
+
+func (as *AuthService) getDummyUser() models.User {
+	as.dummyUserOnce.Do(func() {
+		hash, _ := as.userService.getPasswordHash("dummy password")
+		as.dummyUser = models.User{HashedPassword: hash}
+	})
+
+	return as.dummyUser
+}
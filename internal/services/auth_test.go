@@ -0,0 +1,163 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"rwa/internal/models"
+	"rwa/internal/repository/ram"
+	"rwa/internal/services"
+	"rwa/pkg/clock"
+	"rwa/pkg/passwordcryptor"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAuthService(t *testing.T, clk clock.Clock) *services.AuthService {
+	ctx := context.Background()
+	us := services.NewUserService(ram.NewUserRepository(), ram.NewFollowRepository(), passwordcryptor.Bcrypt{Cost: bcrypt.MinCost}, services.IdentityPolicy{})
+
+	_, err := us.CreateUser(ctx, models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "secret"})
+	if err != nil {
+		t.Fatalf("cannot create user: %v", err)
+	}
+
+	return services.NewAuthService(us, ram.NewLoginAttemptRepository(), clk, services.LoginPolicy{
+		MaxAccountFailures: 3,
+		MaxIPFailures:      5,
+		FailuresWindow:     time.Minute,
+		LockDuration:       10 * time.Minute,
+	})
+}
+
+func TestAuthServiceLogin(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuthService(t, clock.Real{})
+
+	user, err := auth.Login(ctx, "user@example.com", "secret", "10.0.0.1")
+	if err != nil || user.Username != "user" {
+		t.Fatalf("valid credentials rejected: %v", err)
+	}
+
+	_, errWrongPass := auth.Login(ctx, "user@example.com", "wrong", "10.0.0.1")
+	_, errUnknown := auth.Login(ctx, "nobody@example.com", "secret", "10.0.0.1")
+	if !errors.Is(errWrongPass, models.ErrInvalidCredentials) || !errors.Is(errUnknown, models.ErrInvalidCredentials) {
+		t.Fatalf("want ErrInvalidCredentials for both, have: %v, %v", errWrongPass, errUnknown)
+	}
+}
+
+func TestAuthServiceAccountLockout(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	auth := newTestAuthService(t, clk)
+
+	for i := 0; i < 3; i++ {
+		_, err := auth.Login(ctx, "user@example.com", "wrong", "10.0.0.1")
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: want ErrInvalidCredentials, have: %v", i, err)
+		}
+	}
+
+	_, err := auth.Login(ctx, "user@example.com", "secret", "10.0.0.2")
+	if !errors.Is(err, models.ErrTooManyAttempts) {
+		t.Fatalf("locked account: want ErrTooManyAttempts, have: %v", err)
+	}
+
+	clk.Advance(10*time.Minute + time.Second)
+
+	_, err = auth.Login(ctx, "user@example.com", "secret", "10.0.0.2")
+	if err != nil {
+		t.Fatalf("lock must expire: %v", err)
+	}
+}
+
+func TestAuthServiceLockoutEmailVariants(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	auth := newTestAuthService(t, clk)
+
+	// every variant logs into the same account, so they share failure counter
+	for i, email := range []string{" user@example.com", "ＵＳＥＲ@example.com", "User@Example.com "} {
+		_, err := auth.Login(ctx, email, "wrong", "10.0.0."+string(rune('1'+i)))
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Fatalf("variant %q: want ErrInvalidCredentials, have: %v", email, err)
+		}
+	}
+
+	_, err := auth.Login(ctx, "user@example.com", "secret", "10.0.0.9")
+	if !errors.Is(err, models.ErrTooManyAttempts) {
+		t.Fatalf("locked account: want ErrTooManyAttempts, have: %v", err)
+	}
+}
+
+func TestAuthServiceFailuresWindow(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	auth := newTestAuthService(t, clk)
+
+	for i := 0; i < 4; i++ {
+		auth.Login(ctx, "user@example.com", "wrong", "10.0.0.1")
+		if i == 1 {
+			clk.Advance(2 * time.Minute)
+		}
+	}
+
+	_, err := auth.Login(ctx, "user@example.com", "secret", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("old failures must be forgotten after window: %v", err)
+	}
+}
+
+func TestAuthServiceIPThrottling(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	auth := newTestAuthService(t, clk)
+
+	for i := 0; i < 5; i++ {
+		auth.Login(ctx, "victim"+string(rune('a'+i))+"@example.com", "guess", "10.0.0.1")
+	}
+
+	_, err := auth.Login(ctx, "user@example.com", "secret", "10.0.0.1")
+	if !errors.Is(err, models.ErrTooManyAttempts) {
+		t.Fatalf("throttled ip: want ErrTooManyAttempts, have: %v", err)
+	}
+
+	_, err = auth.Login(ctx, "user@example.com", "secret", "10.0.0.2")
+	if err != nil {
+		t.Fatalf("other ip must not be throttled: %v", err)
+	}
+}
+
+func TestAuthServiceDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	auth := newTestAuthService(t, clk)
+
+	for i := 0; i < 3; i++ {
+		auth.Login(ctx, "user@example.com", "wrong", "10.0.0.1")
+	}
+
+	n, err := auth.DeleteExpired(ctx)
+	if err != nil || n != 0 {
+		t.Fatalf("fresh attempts must be kept, have: %d, %v", n, err)
+	}
+
+	// ip failures are out of window, account lock still holds
+	clk.Advance(2 * time.Minute)
+	n, err = auth.DeleteExpired(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("want 1 stale ip entry deleted, have: %d, %v", n, err)
+	}
+
+	_, err = auth.Login(ctx, "user@example.com", "secret", "10.0.0.2")
+	if !errors.Is(err, models.ErrTooManyAttempts) {
+		t.Fatalf("sweep must keep lock: want ErrTooManyAttempts, have: %v", err)
+	}
+
+	clk.Advance(10 * time.Minute)
+	n, err = auth.DeleteExpired(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("want expired lock deleted, have: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"rwa/internal/models"
+	"time"
+)
+
+type CommentRepository interface {
+	GetById(ctx context.Context, id int64) (*models.Comment, error)
+	GetAllByArticle(ctx context.Context, slug string) ([]*models.Comment, error)
+	Save(ctx context.Context, comment models.Comment) (int64, error)
+	Delete(ctx context.Context, comment models.Comment) error
+	DeleteAllByArticle(ctx context.Context, slug string) error
+	UpdateSlug(ctx context.Context, oldSlug, newSlug string) error
+}
+
+type CommentService struct {
+	commentRepo CommentRepository
+	userService *UserService
+}
+
+func NewCommentService(commentRepo CommentRepository, us *UserService) *CommentService {
+	return &CommentService{
+		commentRepo: commentRepo,
+		userService: us,
+	}
+}
+
+func (cs *CommentService) CreateComment(ctx context.Context, user models.User, article models.Article, info models.CommentInfo) (*models.Comment, error) {
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now()
+	comment := models.Comment{
+		ArticleSlug: article.Slug,
+		Author:      user.ToProfile(),
+		Body:        info.Body,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+
+	id, err := cs.commentRepo.Save(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	comment.ID = id
+
+	return &comment, nil
+}
+
+// viewer may be nil for anonymous requests, then following is always false
+func (cs *CommentService) GetAllByArticle(ctx context.Context, viewer *models.User, article models.Article) ([]*models.Comment, error) {
+	comments, err := cs.commentRepo.GetAllByArticle(ctx, article.Slug)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range comments {
+		err = cs.fillAuthor(ctx, viewer, c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return comments, nil
+}
+
+func (cs *CommentService) GetById(ctx context.Context, article models.Article, id int64) (*models.Comment, error) {
+	comment, err := cs.commentRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.ArticleSlug != article.Slug {
+		return nil, models.ErrNotFound
+	}
+
+	return comment, nil
+}
+
+func (cs *CommentService) DeleteComment(ctx context.Context, user models.User, comment models.Comment) error {
+	if comment.Author.ID != user.ID {
+		return models.ErrPermissionDenied
+	}
+
+	return cs.commentRepo.Delete(ctx, comment)
+}
+
+func (cs *CommentService) fillAuthor(ctx context.Context, viewer *models.User, c *models.Comment) error {
+	author, err := cs.userService.GetUserById(ctx, c.Author.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	profile, err := cs.userService.GetProfile(ctx, viewer, *author)
+	if err != nil {
+		return err
+	}
+	c.Author = *profile
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"net/mail"
+	"os"
+	"regexp"
+	"rwa/internal/models"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// IdentityPolicy validates credentials and profile fields of users
+type IdentityPolicy struct {
+	MinPasswordLength int
+	// PasswordClasses is minimal number of used character classes:
+	// lowercase, uppercase, digits and others
+	PasswordClasses int
+	// CommonPasswords are rejected regardless of case
+	CommonPasswords map[string]struct{}
+
+	MinUsernameLength int
+	MaxUsernameLength int
+	UsernamePattern   *regexp.Regexp
+}
+
+// LoadCommonPasswords reads denylist with one password per line
+func LoadCommonPasswords(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	passwords := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			passwords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+
+	return passwords, scanner.Err()
+}
+
+// NormalizeCreate prepares fields for validation and storage
+func (p IdentityPolicy) NormalizeCreate(info models.UserCreateInfo) models.UserCreateInfo {
+	info.Email = normalizeEmail(info.Email)
+	info.Username = normalizeUsername(info.Username)
+
+	return info
+}
+
+func (p IdentityPolicy) NormalizeUpdate(info models.UserUpdateInfo) models.UserUpdateInfo {
+	info.Email = normalizeEmail(info.Email)
+	info.Username = normalizeUsername(info.Username)
+
+	return info
+}
+
+func (p IdentityPolicy) ValidateCreate(info models.UserCreateInfo) error {
+	errs := models.ValidationErrors{}
+
+	p.checkEmail(errs, info.Email)
+	p.checkUsername(errs, info.Username)
+	p.checkPassword(errs, "password", info.Password)
+
+	return errs.Err()
+}
+
+// ValidateUpdate checks only fields which are changed
+func (p IdentityPolicy) ValidateUpdate(info models.UserUpdateInfo) error {
+	errs := models.ValidationErrors{}
+
+	if info.Email != "" {
+		p.checkEmail(errs, info.Email)
+	}
+	if info.Username != "" {
+		p.checkUsername(errs, info.Username)
+	}
+
+	return errs.Err()
+}
+
+// ValidatePassword reports problems under field name
+func (p IdentityPolicy) ValidatePassword(field string, password string) error {
+	errs := models.ValidationErrors{}
+
+	p.checkPassword(errs, field, password)
+
+	return errs.Err()
+}
+
+func (p IdentityPolicy) checkEmail(errs models.ValidationErrors, email string) {
+	if email == "" {
+		errs.Add("email", "can't be empty")
+		return
+	}
+
+	// RFC 5322 addr-spec only, without display name and comments
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		errs.Add("email", "is invalid")
+	}
+}
+
+func (p IdentityPolicy) checkUsername(errs models.ValidationErrors, username string) {
+	if username == "" {
+		errs.Add("username", "can't be empty")
+		return
+	}
+
+	length := utf8.RuneCountInString(username)
+	if length < p.MinUsernameLength {
+		errs.Add("username", fmt.Sprintf("is too short (minimum is %d characters)", p.MinUsernameLength))
+	}
+	if p.MaxUsernameLength > 0 && length > p.MaxUsernameLength {
+		errs.Add("username", fmt.Sprintf("is too long (maximum is %d characters)", p.MaxUsernameLength))
+	}
+	if p.UsernamePattern != nil && !p.UsernamePattern.MatchString(username) {
+		errs.Add("username", "contains forbidden characters")
+	}
+}
+
+func (p IdentityPolicy) checkPassword(errs models.ValidationErrors, field string, password string) {
+	if password == "" {
+		errs.Add(field, "can't be empty")
+		return
+	}
+
+	if utf8.RuneCountInString(password) < p.MinPasswordLength {
+		errs.Add(field, fmt.Sprintf("is too short (minimum is %d characters)", p.MinPasswordLength))
+	}
+	if passwordClasses(password) < p.PasswordClasses {
+		errs.Add(field, fmt.Sprintf("must contain at least %d of lowercase, uppercase, digits and other characters", p.PasswordClasses))
+	}
+	if _, common := p.CommonPasswords[strings.ToLower(password)]; common {
+		errs.Add(field, "is too common")
+	}
+}
+
+func passwordClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	classes := 0
+	for _, used := range []bool{lower, upper, digit, other} {
+		if used {
+			classes++
+		}
+	}
+
+	return classes
+}
+
+// emails are stored in the form they are unique in
+func normalizeEmail(email string) string {
+	return models.NormalizeIdentity(strings.TrimSpace(email))
+}
+
+// usernames keep their case, repository compares them case insensitively
+func normalizeUsername(username string) string {
+	return norm.NFKC.String(strings.TrimSpace(username))
+}
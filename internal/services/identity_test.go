@@ -0,0 +1,80 @@
+package services_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"rwa/internal/models"
+	"rwa/internal/services"
+	"testing"
+)
+
+func newTestIdentityPolicy(t *testing.T) services.IdentityPolicy {
+	denylist := filepath.Join(t.TempDir(), "common.txt")
+	os.WriteFile(denylist, []byte("password1\nQwerty123\n"), 0o600)
+
+	common, err := services.LoadCommonPasswords(denylist)
+	if err != nil {
+		t.Fatalf("cannot load common passwords: %v", err)
+	}
+
+	return services.IdentityPolicy{
+		MinPasswordLength: 8,
+		PasswordClasses:   2,
+		CommonPasswords:   common,
+		MinUsernameLength: 3,
+		MaxUsernameLength: 8,
+		UsernamePattern:   regexp.MustCompile(`^[\p{L}\p{N}_]+$`),
+	}
+}
+
+func TestIdentityPolicyValidateCreate(t *testing.T) {
+	policy := newTestIdentityPolicy(t)
+
+	cases := []struct {
+		info   models.UserCreateInfo
+		fields []string
+	}{
+		{models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "correct horse 1"}, nil},
+		{models.UserCreateInfo{Email: "user@example.com", Username: "юзер_1", Password: "Secret12"}, nil},
+		{models.UserCreateInfo{}, []string{"email", "username", "password"}},
+		{models.UserCreateInfo{Email: "User <user@example.com>", Username: "user", Password: "Secret12"}, []string{"email"}},
+		{models.UserCreateInfo{Email: "user@example.com", Username: "us", Password: "Secret12"}, []string{"username"}},
+		{models.UserCreateInfo{Email: "user@example.com", Username: "too_long_name", Password: "Secret12"}, []string{"username"}},
+		{models.UserCreateInfo{Email: "user@example.com", Username: "us-er", Password: "Secret12"}, []string{"username"}},
+		{models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "Sec12"}, []string{"password"}},
+		{models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "lowercaseonly"}, []string{"password"}},
+		{models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "QWERTY123"}, []string{"password"}},
+	}
+
+	for i, c := range cases {
+		err := policy.ValidateCreate(policy.NormalizeCreate(c.info))
+
+		var errs models.ValidationErrors
+		if len(c.fields) == 0 {
+			if err != nil {
+				t.Fatalf("case %d: want no errors, have: %v", i, err)
+			}
+			continue
+		}
+
+		if !errors.As(err, &errs) || len(errs) != len(c.fields) {
+			t.Fatalf("case %d: want errors for %v, have: %v", i, c.fields, err)
+		}
+		for _, field := range c.fields {
+			if len(errs[field]) == 0 {
+				t.Fatalf("case %d: want error for %s, have: %v", i, field, err)
+			}
+		}
+	}
+}
+
+func TestIdentityPolicyNormalize(t *testing.T) {
+	policy := newTestIdentityPolicy(t)
+
+	info := policy.NormalizeCreate(models.UserCreateInfo{Email: " User@ＥＸＡＭＰＬＥ.com ", Username: "ｕｓｅｒ"})
+	if info.Email != "user@example.com" || info.Username != "user" {
+		t.Fatalf("want normalized email and username, have: %q, %q", info.Email, info.Username)
+	}
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Sweep removes expired data and returns number of removed items
+type Sweep func(ctx context.Context) (int, error)
+
+// RunJanitor runs every sweep each interval until ctx is done, sweeps are keyed by name for logs
+func RunJanitor(ctx context.Context, interval time.Duration, sweeps map[string]Sweep) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for name, sweep := range sweeps {
+				if _, err := sweep(ctx); err != nil {
+					fmt.Printf("%s janitor: %v\n", name, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rwa/internal/models"
+	"rwa/pkg/clock"
+	"rwa/pkg/mailer"
+	"time"
+)
+
+type PasswordResetPolicy struct {
+	TokenTTL time.Duration
+}
+
+type PasswordService struct {
+	userService *UserService
+	sessions    *SessionManager
+	tokenRepo   UserTokenRepository
+	mailer      mailer.Mailer
+	clock       clock.Clock
+	policy      PasswordResetPolicy
+}
+
+func NewPasswordService(us *UserService, sm *SessionManager, tokenRepo UserTokenRepository, m mailer.Mailer, clk clock.Clock, policy PasswordResetPolicy) *PasswordService {
+	return &PasswordService{
+		userService: us,
+		sessions:    sm,
+		tokenRepo:   tokenRepo,
+		mailer:      m,
+		clock:       clk,
+		policy:      policy,
+	}
+}
+
+// ChangePassword checks current password and revokes all sessions of user
+// but the current one, so client keeps its tokens
+func (ps *PasswordService) ChangePassword(ctx context.Context, user models.User, currentSessionId string, info models.PasswordChangeInfo) (*models.User, error) {
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := ps.userService.ValidatePassword("newPassword", info.NewPassword); err != nil {
+		return nil, err
+	}
+
+	if !ps.userService.VerificatePassword(ctx, user, info.CurrentPassword) {
+		return nil, fmt.Errorf("%w: wrong current password", models.ErrPermissionDenied)
+	}
+
+	updated, err := ps.userService.SetPassword(ctx, user, info.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ps.sessions.DeleteOthers(ctx, *updated, currentSessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// RequestReset mails reset token to user. Unknown email is not an error,
+// so response does not tell whether account exists
+func (ps *PasswordService) RequestReset(ctx context.Context, email string) error {
+	user, err := ps.userService.GetByEmail(ctx, email)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := issueUserToken(ctx, ps.tokenRepo, *user, models.PurposePasswordReset, ps.clock.Now(), ps.policy.TokenTTL, 0)
+	if err != nil {
+		return err
+	}
+
+	return ps.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body:    fmt.Sprintf("Use this token to set new password, it is valid for %s:\n\n%s", ps.policy.TokenTTL, token),
+	})
+}
+
+// ConfirmReset sets new password by reset token and revokes all sessions of user
+func (ps *PasswordService) ConfirmReset(ctx context.Context, token string, password string) error {
+	if err := ps.userService.ValidatePassword("password", password); err != nil {
+		return err
+	}
+
+	rt, err := useUserToken(ctx, ps.tokenRepo, token, models.PurposePasswordReset, ps.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	user, err := ps.userService.GetUserById(ctx, rt.UserId)
+	if err != nil {
+		return err
+	}
+
+	updated, err := ps.userService.SetPassword(ctx, *user, password)
+	if err != nil {
+		return err
+	}
+
+	return ps.sessions.DeleteAllByUser(ctx, *updated)
+}
@@ -0,0 +1,170 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"rwa/internal/models"
+	"rwa/internal/repository/ram"
+	"rwa/internal/services"
+	"rwa/pkg/clock"
+	"rwa/pkg/mailer"
+	"rwa/pkg/passwordcryptor"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type passwordTestEnv struct {
+	ps     *services.PasswordService
+	us     *services.UserService
+	sm     *services.SessionManager
+	mails  *mailer.Memory
+	clock  *clock.Fake
+	user   models.User
+	client models.ClientInfo
+}
+
+func newPasswordTestEnv(t *testing.T) passwordTestEnv {
+	ctx := context.Background()
+	env := passwordTestEnv{
+		mails: mailer.NewMemory(),
+		clock: clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+
+	env.us = services.NewUserService(ram.NewUserRepository(), ram.NewFollowRepository(), passwordcryptor.Bcrypt{Cost: bcrypt.MinCost}, services.IdentityPolicy{})
+	env.sm = services.NewSessionManager(ram.NewSessionRepository(), env.us, services.OpaqueTokens{}, env.clock, services.SessionPolicy{
+		TTL:         time.Hour,
+		IdleTimeout: time.Hour,
+		RefreshTTL:  24 * time.Hour,
+	})
+	env.ps = services.NewPasswordService(env.us, env.sm, ram.NewUserTokenRepository(), env.mails, env.clock, services.PasswordResetPolicy{
+		TokenTTL: 10 * time.Minute,
+	})
+
+	user, err := env.us.CreateUser(ctx, models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "secret"})
+	if err != nil {
+		t.Fatalf("cannot create user: %v", err)
+	}
+	env.user = *user
+
+	return env
+}
+
+// lastResetToken takes token from the last line of the last mail
+func (env passwordTestEnv) lastResetToken(t *testing.T) string {
+	msgs := env.mails.Messages(env.user.Email)
+	if len(msgs) == 0 {
+		t.Fatalf("no reset mail sent")
+	}
+
+	lines := strings.Split(msgs[len(msgs)-1].Body, "\n")
+	return lines[len(lines)-1]
+}
+
+func (env passwordTestEnv) checkPassword(t *testing.T, password string) {
+	ctx := context.Background()
+	user, _ := env.us.GetUserById(ctx, env.user.ID)
+	if !env.us.VerificatePassword(ctx, *user, password) {
+		t.Fatalf("password is not %q", password)
+	}
+}
+
+func TestPasswordServiceChange(t *testing.T) {
+	ctx := context.Background()
+	env := newPasswordTestEnv(t)
+
+	_, sweptPair, _ := env.sm.Create(ctx, env.user, env.client)
+	env.clock.Advance(2 * time.Hour)
+	// janitor removes idle session, its refresh token is still valid
+	if _, err := env.sm.DeleteExpired(ctx); err != nil {
+		t.Fatalf("cannot delete expired sessions: %v", err)
+	}
+
+	current, currentPair, _ := env.sm.Create(ctx, env.user, env.client)
+	other, otherPair, _ := env.sm.Create(ctx, env.user, env.client)
+
+	_, err := env.ps.ChangePassword(ctx, env.user, current.ID, models.PasswordChangeInfo{CurrentPassword: "wrong", NewPassword: "new"})
+	if !errors.Is(err, models.ErrPermissionDenied) {
+		t.Fatalf("wrong current password: want ErrPermissionDenied, have: %v", err)
+	}
+
+	if _, err := env.ps.ChangePassword(ctx, env.user, current.ID, models.PasswordChangeInfo{CurrentPassword: "secret", NewPassword: "new"}); err != nil {
+		t.Fatalf("cannot change password: %v", err)
+	}
+	env.checkPassword(t, "new")
+
+	if _, err := env.sm.Get(ctx, other.ID, otherPair.AccessToken); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("other session: want ErrNotFound, have: %v", err)
+	}
+	if _, _, err := env.sm.Refresh(ctx, otherPair.RefreshToken, env.client); err == nil {
+		t.Fatalf("refresh token of other session still works")
+	}
+	if _, _, err := env.sm.Refresh(ctx, sweptPair.RefreshToken, env.client); err == nil {
+		t.Fatalf("refresh token of swept session still works")
+	}
+
+	if _, err := env.sm.Get(ctx, current.ID, currentPair.AccessToken); err != nil {
+		t.Fatalf("current session rejected: %v", err)
+	}
+	if _, _, err := env.sm.Refresh(ctx, currentPair.RefreshToken, env.client); err != nil {
+		t.Fatalf("cannot refresh current session: %v", err)
+	}
+}
+
+func TestPasswordServiceReset(t *testing.T) {
+	ctx := context.Background()
+	env := newPasswordTestEnv(t)
+
+	if err := env.ps.RequestReset(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("unknown email: want no error, have: %v", err)
+	}
+
+	session, pair, _ := env.sm.Create(ctx, env.user, env.client)
+
+	env.ps.RequestReset(ctx, env.user.Email)
+	token := env.lastResetToken(t)
+
+	if err := env.ps.ConfirmReset(ctx, token+"x", "new"); !errors.Is(err, models.ErrInvalidToken) {
+		t.Fatalf("forged token: want ErrInvalidToken, have: %v", err)
+	}
+
+	if err := env.ps.ConfirmReset(ctx, token, "new"); err != nil {
+		t.Fatalf("cannot reset password: %v", err)
+	}
+	env.checkPassword(t, "new")
+
+	if _, err := env.sm.Get(ctx, session.ID, pair.AccessToken); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("session after reset: want ErrNotFound, have: %v", err)
+	}
+
+	if err := env.ps.ConfirmReset(ctx, token, "newer"); !errors.Is(err, models.ErrInvalidToken) {
+		t.Fatalf("used token: want ErrInvalidToken, have: %v", err)
+	}
+}
+
+func TestPasswordServiceResetExpiredOrReplaced(t *testing.T) {
+	ctx := context.Background()
+	env := newPasswordTestEnv(t)
+
+	env.ps.RequestReset(ctx, env.user.Email)
+	expired := env.lastResetToken(t)
+	env.clock.Advance(11 * time.Minute)
+
+	if err := env.ps.ConfirmReset(ctx, expired, "new"); !errors.Is(err, models.ErrInvalidToken) {
+		t.Fatalf("expired token: want ErrInvalidToken, have: %v", err)
+	}
+
+	env.ps.RequestReset(ctx, env.user.Email)
+	replaced := env.lastResetToken(t)
+	env.ps.RequestReset(ctx, env.user.Email)
+
+	if err := env.ps.ConfirmReset(ctx, replaced, "new"); !errors.Is(err, models.ErrInvalidToken) {
+		t.Fatalf("replaced token: want ErrInvalidToken, have: %v", err)
+	}
+	if err := env.ps.ConfirmReset(ctx, env.lastResetToken(t), "new"); err != nil {
+		t.Fatalf("latest token rejected: %v", err)
+	}
+	env.checkPassword(t, "new")
+}
@@ -1,79 +1,311 @@
 package services
 
 import (
-	"crypto/md5"
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
 	"rwa/internal/models"
+	"rwa/pkg/clock"
+	"sort"
 	"time"
 )
 
 const sessionIdLength = 16
 
 type SessionRepository interface {
-	Get(sessionId string) (*models.Session, error)
-	GetAllByUser(userId int64) ([]*models.Session, error)
-	Save(models.Session) error
-	DeleteAllByUser(userId int64) error
-	Delete(sessionId string) error
+	Get(ctx context.Context, sessionId string) (*models.Session, error)
+	GetAllByUser(ctx context.Context, userId int64) ([]*models.Session, error)
+	Save(ctx context.Context, session models.Session) error
+	UpdateLastSeen(ctx context.Context, sessionId string, lastSeenAt time.Time) error
+	DeleteAllByUser(ctx context.Context, userId int64) error
+	Delete(ctx context.Context, sessionId string) error
+	DeleteExpired(ctx context.Context, createdBefore, lastSeenBefore time.Time) (int, error)
+
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, id string) (*models.RefreshToken, error)
+	// UseRefreshToken returns models.ErrAlreadyUsed if token was used before
+	UseRefreshToken(ctx context.Context, id string) error
+	// RotateRefreshToken uses token usedId, deletes its session and saves new session with its refresh token
+	// in one step. Nothing is changed on error, models.ErrAlreadyUsed is returned for used token
+	RotateRefreshToken(ctx context.Context, usedId string, session models.Session, token models.RefreshToken) error
+	DeleteFamily(ctx context.Context, familyId string) error
+	// DeleteFamiliesExcept removes sessions and refresh tokens of user from all families but familyId,
+	// including refresh tokens which outlived their sessions
+	DeleteFamiliesExcept(ctx context.Context, userId int64, familyId string) error
+	DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int, error)
+}
+
+type SessionPolicy struct {
+	// TTL is absolute session lifetime since login
+	TTL time.Duration
+	// IdleTimeout expires session not used for this time
+	IdleTimeout time.Duration
+	// RefreshTTL is lifetime of refresh token since it was issued
+	RefreshTTL time.Duration
 }
 
 type SessionManager struct {
 	sessionRepo SessionRepository
 	userService *UserService
+	tokens      TokenStrategy
+	clock       clock.Clock
+	policy      SessionPolicy
 }
 
-func NewSessionManager(sesRepo SessionRepository, us *UserService) *SessionManager {
+func NewSessionManager(sesRepo SessionRepository, us *UserService, tokens TokenStrategy, clk clock.Clock, policy SessionPolicy) *SessionManager {
 	return &SessionManager{
 		sessionRepo: sesRepo,
 		userService: us,
+		tokens:      tokens,
+		clock:       clk,
+		policy:      policy,
 	}
 }
 
-func (sm *SessionManager) Get(sessionId string) (*models.Session, error) {
-	return sm.sessionRepo.Get(sessionId)
+// Get finds session by id parsed from client token and checks token against stored digest.
+// Only alive sessions are returned, for expired one models.ErrNotFound is returned.
+// Expired session is left to janitor, so its refresh token can still be used within RefreshTTL
+func (sm *SessionManager) Get(ctx context.Context, sessionId string, token string) (*models.Session, error) {
+	session, err := sm.sessionRepo.Get(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashToken(token)
+	if subtle.ConstantTimeCompare(hash[:], session.TokenHash) != 1 {
+		return nil, models.ErrNotFound
+	}
+
+	if sm.isExpired(*session) {
+		return nil, models.ErrNotFound
+	}
+
+	return session, nil
 }
 
-func (sm *SessionManager) GetAllByUser(user models.User) ([]*models.Session, error) {
-	return sm.sessionRepo.GetAllByUser(user.ID)
+// Touch prolongs idle timeout of session
+func (sm *SessionManager) Touch(ctx context.Context, session models.Session) error {
+	return sm.sessionRepo.UpdateLastSeen(ctx, session.ID, sm.clock.Now())
 }
 
-func (sm *SessionManager) Create(user models.User) (*models.Session, error) {
-	sessionId, err := sm.generateSessionId()
+// GetAllByUser returns alive user sessions, recently used first.
+// Expired ones are skipped like in Get, even if janitor has not removed them yet
+func (sm *SessionManager) GetAllByUser(ctx context.Context, user models.User) ([]*models.Session, error) {
+	all, err := sm.sessionRepo.GetAllByUser(ctx, user.ID)
+	if errors.Is(err, models.ErrNotFound) {
+		return []*models.Session{}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	sessions := make([]*models.Session, 0, len(all))
+	for _, s := range all {
+		if !sm.isExpired(*s) {
+			sessions = append(sessions, s)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+// Create starts new session family on login, tokens are returned to client only
+func (sm *SessionManager) Create(ctx context.Context, user models.User, client models.ClientInfo) (*models.Session, models.TokenPair, error) {
+	familyId, err := generateId()
+	if err != nil {
+		return nil, models.TokenPair{}, err
+	}
+
+	return sm.create(ctx, user, client, familyId)
+}
+
+// Refresh exchanges refresh token for new token pair, old session is deleted.
+// Reuse of refresh token revokes whole family, as one of its holders is not legitimate
+func (sm *SessionManager) Refresh(ctx context.Context, refreshToken string, client models.ClientInfo) (*models.Session, models.TokenPair, error) {
+	id, err := OpaqueTokens{}.Parse(refreshToken)
+	if err != nil {
+		return nil, models.TokenPair{}, models.ErrInvalidCredentials
+	}
+
+	rt, err := sm.sessionRepo.GetRefreshToken(ctx, id)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, models.TokenPair{}, models.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, models.TokenPair{}, err
+	}
+
+	hash := hashToken(refreshToken)
+	if subtle.ConstantTimeCompare(hash[:], rt.TokenHash) != 1 || !sm.clock.Now().Before(rt.ExpiresAt) {
+		return nil, models.TokenPair{}, models.ErrInvalidCredentials
+	}
+
+	// token is consumed together with saving its replacement,
+	// so failure before that leaves it usable for retry
+	user, err := sm.userService.GetUserById(ctx, rt.UserId)
+	if err != nil {
+		return nil, models.TokenPair{}, err
+	}
+
+	session, newRt, pair, err := sm.newSession(*user, client, rt.FamilyId)
+	if err != nil {
+		return nil, models.TokenPair{}, err
+	}
+
+	err = sm.sessionRepo.RotateRefreshToken(ctx, rt.ID, *session, newRt)
+	if errors.Is(err, models.ErrAlreadyUsed) {
+		if err := sm.sessionRepo.DeleteFamily(ctx, rt.FamilyId); err != nil {
+			return nil, models.TokenPair{}, err
+		}
+		return nil, models.TokenPair{}, models.ErrInvalidCredentials
+	}
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, models.TokenPair{}, models.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, models.TokenPair{}, err
+	}
+
+	return session, pair, nil
+}
+
+func (sm *SessionManager) create(ctx context.Context, user models.User, client models.ClientInfo, familyId string) (*models.Session, models.TokenPair, error) {
+	session, rt, pair, err := sm.newSession(user, client, familyId)
+	if err != nil {
+		return nil, models.TokenPair{}, err
+	}
+
+	err = sm.sessionRepo.Save(ctx, *session)
+	if err != nil {
+		return nil, models.TokenPair{}, err
+	}
+
+	err = sm.sessionRepo.SaveRefreshToken(ctx, rt)
+	if err != nil {
+		return nil, models.TokenPair{}, err
+	}
+
+	return session, pair, nil
+}
+
+// newSession makes session with its refresh token, only hashes of returned tokens are kept in them
+func (sm *SessionManager) newSession(user models.User, client models.ClientInfo, familyId string) (*models.Session, models.RefreshToken, models.TokenPair, error) {
+	sessionId, err := generateId()
+	if err != nil {
+		return nil, models.RefreshToken{}, models.TokenPair{}, err
+	}
+
+	now := sm.clock.Now()
 	session := models.Session{
-		UserId:    user.ID,
+		ID:         sessionId,
+		UserId:     user.ID,
+		FamilyId:   familyId,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+	}
+
+	accessToken, err := sm.tokens.Issue(session, now.Add(sm.policy.TTL))
+	if err != nil {
+		return nil, models.RefreshToken{}, models.TokenPair{}, err
+	}
+
+	hash := hashToken(accessToken)
+	session.TokenHash = hash[:]
+
+	refreshId, err := generateId()
+	if err != nil {
+		return nil, models.RefreshToken{}, models.TokenPair{}, err
+	}
+
+	refreshToken, err := newOpaqueToken(refreshId)
+	if err != nil {
+		return nil, models.RefreshToken{}, models.TokenPair{}, err
+	}
+
+	refreshHash := hashToken(refreshToken)
+	rt := models.RefreshToken{
+		ID:        refreshId,
+		FamilyId:  familyId,
 		SessionId: sessionId,
-		CreatedAt: time.Now(),
+		UserId:    user.ID,
+		TokenHash: refreshHash[:],
+		CreatedAt: now,
+		ExpiresAt: now.Add(sm.policy.RefreshTTL),
 	}
 
-	err = sm.sessionRepo.Save(session)
+	return &session, rt, models.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (sm *SessionManager) Delete(ctx context.Context, sessionId string) error {
+	return sm.sessionRepo.Delete(ctx, sessionId)
+}
+
+// Revoke deletes one of user sessions, sessions of other users are not found
+func (sm *SessionManager) Revoke(ctx context.Context, user models.User, sessionId string) error {
+	session, err := sm.sessionRepo.Get(ctx, sessionId)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if session.UserId != user.ID {
+		return models.ErrNotFound
 	}
 
-	return &session, nil
+	return sm.sessionRepo.Delete(ctx, sessionId)
 }
 
-func (sm *SessionManager) Delete(sessionId string) error {
-	return sm.sessionRepo.Delete(sessionId)
+func (sm *SessionManager) DeleteAllByUser(ctx context.Context, user models.User) error {
+	return sm.sessionRepo.DeleteAllByUser(ctx, user.ID)
 }
 
-func (sm *SessionManager) DeleteAllByUser(user models.User) error {
-	return sm.sessionRepo.DeleteAllByUser(user.ID)
+// DeleteOthers revokes every session family of user except the one of current session
+func (sm *SessionManager) DeleteOthers(ctx context.Context, user models.User, currentSessionId string) error {
+	current, err := sm.sessionRepo.Get(ctx, currentSessionId)
+	if err != nil {
+		return err
+	}
+
+	return sm.sessionRepo.DeleteFamiliesExcept(ctx, user.ID, current.FamilyId)
 }
 
-func (sm *SessionManager) generateSessionId() (string, error) {
+// DeleteExpired removes expired sessions and refresh tokens, returns number of removed sessions
+func (sm *SessionManager) DeleteExpired(ctx context.Context) (int, error) {
+	now := sm.clock.Now()
+
+	deleted, err := sm.sessionRepo.DeleteExpired(ctx, now.Add(-sm.policy.TTL), now.Add(-sm.policy.IdleTimeout))
+	if err != nil {
+		return deleted, err
+	}
+
+	_, err = sm.sessionRepo.DeleteExpiredRefreshTokens(ctx, now)
+
+	return deleted, err
+}
+
+func (sm *SessionManager) isExpired(session models.Session) bool {
+	now := sm.clock.Now()
+
+	return now.Sub(session.CreatedAt) > sm.policy.TTL || now.Sub(session.LastSeenAt) > sm.policy.IdleTimeout
+}
+
+func generateId() (string, error) {
 	b := make([]byte, sessionIdLength)
 	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
 
-	hasher := md5.New()
-	hasher.Write(b)
+	return hex.EncodeToString(b), nil
+}
 
-	return hex.EncodeToString(hasher.Sum(nil)), nil
+func hashToken(token string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(token))
 }
@@ -0,0 +1,285 @@
+package services_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"rwa/internal/models"
+	"rwa/internal/repository/ram"
+	"rwa/internal/services"
+	"rwa/pkg/clock"
+	"rwa/pkg/passwordcryptor"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestSessionManager(clk clock.Clock) *services.SessionManager {
+	return services.NewSessionManager(ram.NewSessionRepository(), nil, services.OpaqueTokens{}, clk, services.SessionPolicy{
+		TTL:         24 * time.Hour,
+		IdleTimeout: time.Hour,
+	})
+}
+
+func TestSessionManagerIdleTimeout(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sm := newTestSessionManager(clk)
+
+	session, pair, err := sm.Create(ctx, models.User{ID: 1}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("cannot create session: %v", err)
+	}
+
+	// every use slides idle timeout
+	for i := 0; i < 3; i++ {
+		clk.Advance(50 * time.Minute)
+		s, err := sm.Get(ctx, session.ID, pair.AccessToken)
+		if err != nil {
+			t.Fatalf("use %d: session expired too early: %v", i, err)
+		}
+		sm.Touch(ctx, *s)
+	}
+
+	clk.Advance(61 * time.Minute)
+	if _, err := sm.Get(ctx, session.ID, pair.AccessToken); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("idle session: want ErrNotFound, have: %v", err)
+	}
+}
+
+func TestSessionManagerTTL(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sm := newTestSessionManager(clk)
+
+	session, pair, _ := sm.Create(ctx, models.User{ID: 1}, models.ClientInfo{})
+
+	// touching does not prolong absolute lifetime
+	for i := 0; i < 25; i++ {
+		clk.Advance(59 * time.Minute)
+		if s, err := sm.Get(ctx, session.ID, pair.AccessToken); err == nil {
+			sm.Touch(ctx, *s)
+		}
+	}
+
+	if _, err := sm.Get(ctx, session.ID, pair.AccessToken); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("session older than TTL: want ErrNotFound, have: %v", err)
+	}
+}
+
+func TestSessionManagerGetAllByUserSkipsExpired(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sm := newTestSessionManager(clk)
+	user := models.User{ID: 1}
+
+	sm.Create(ctx, user, models.ClientInfo{})
+	clk.Advance(50 * time.Minute)
+	alive, _, _ := sm.Create(ctx, user, models.ClientInfo{})
+
+	// first session is idle, janitor has not run yet
+	clk.Advance(20 * time.Minute)
+	sessions, err := sm.GetAllByUser(ctx, user)
+	if err != nil || len(sessions) != 1 || sessions[0].ID != alive.ID {
+		t.Fatalf("want only alive session, have: %v, %v", sessions, err)
+	}
+}
+
+func TestSessionManagerDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sm := newTestSessionManager(clk)
+
+	sm.Create(ctx, models.User{ID: 1}, models.ClientInfo{})
+	clk.Advance(2 * time.Hour)
+	alive, alivePair, _ := sm.Create(ctx, models.User{ID: 2}, models.ClientInfo{})
+
+	deleted, err := sm.DeleteExpired(ctx)
+	if err != nil || deleted != 1 {
+		t.Fatalf("want 1 expired session deleted, have: %d, %v", deleted, err)
+	}
+
+	if _, err := sm.Get(ctx, alive.ID, alivePair.AccessToken); err != nil {
+		t.Fatalf("alive session was deleted: %v", err)
+	}
+}
+
+func TestSessionManagerTokenAtRest(t *testing.T) {
+	ctx := context.Background()
+	sesRepo := ram.NewSessionRepository()
+	sm := services.NewSessionManager(sesRepo, nil, services.OpaqueTokens{}, clock.Real{}, services.SessionPolicy{TTL: time.Hour, IdleTimeout: time.Hour})
+
+	session, pair, err := sm.Create(ctx, models.User{ID: 1}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("cannot create session: %v", err)
+	}
+
+	stored, err := sesRepo.Get(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("session is not stored by public id: %v", err)
+	}
+	if len(stored.TokenHash) != sha256.Size {
+		t.Fatalf("want only sha256 digest of token stored, have: %x", stored.TokenHash)
+	}
+
+	if s, err := sm.Get(ctx, session.ID, pair.AccessToken); err != nil || s.ID != session.ID {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	for _, bad := range []string{session.ID, session.ID + ".forged", pair.AccessToken + "x", pair.RefreshToken} {
+		if _, err := sm.Get(ctx, session.ID, bad); !errors.Is(err, models.ErrNotFound) {
+			t.Fatalf("token %q: want ErrNotFound, have: %v", bad, err)
+		}
+	}
+}
+
+func newTestRefreshManager(t *testing.T, clk clock.Clock) (*services.SessionManager, models.User) {
+	ctx := context.Background()
+	us := services.NewUserService(ram.NewUserRepository(), ram.NewFollowRepository(), passwordcryptor.Bcrypt{Cost: bcrypt.MinCost}, services.IdentityPolicy{})
+
+	user, err := us.CreateUser(ctx, models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "secret"})
+	if err != nil {
+		t.Fatalf("cannot create user: %v", err)
+	}
+
+	sm := services.NewSessionManager(ram.NewSessionRepository(), us, services.OpaqueTokens{}, clk, services.SessionPolicy{
+		TTL:         time.Hour,
+		IdleTimeout: time.Hour,
+		RefreshTTL:  24 * time.Hour,
+	})
+
+	return sm, *user
+}
+
+func TestSessionManagerRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	sm, user := newTestRefreshManager(t, clock.Real{})
+
+	session, pair, _ := sm.Create(ctx, user, models.ClientInfo{})
+
+	newSession, newPair, err := sm.Refresh(ctx, pair.RefreshToken, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("cannot refresh: %v", err)
+	}
+
+	if _, err := sm.Get(ctx, session.ID, pair.AccessToken); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("old session: want ErrNotFound, have: %v", err)
+	}
+	if _, err := sm.Get(ctx, newSession.ID, newPair.AccessToken); err != nil {
+		t.Fatalf("new session rejected: %v", err)
+	}
+
+	// second rotation in the same family works as well
+	if _, _, err := sm.Refresh(ctx, newPair.RefreshToken, models.ClientInfo{}); err != nil {
+		t.Fatalf("cannot refresh rotated token: %v", err)
+	}
+}
+
+// flakyUserRepository fails the first lookup like database under load
+type flakyUserRepository struct {
+	services.UserRepository
+	failed bool
+}
+
+func (r *flakyUserRepository) GetById(ctx context.Context, id int64) (*models.User, error) {
+	if !r.failed {
+		r.failed = true
+		return nil, context.DeadlineExceeded
+	}
+
+	return r.UserRepository.GetById(ctx, id)
+}
+
+func TestSessionManagerRefreshRetryAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	userRepo := &flakyUserRepository{UserRepository: ram.NewUserRepository(), failed: true}
+	us := services.NewUserService(userRepo, ram.NewFollowRepository(), passwordcryptor.Bcrypt{Cost: bcrypt.MinCost}, services.IdentityPolicy{})
+	user, _ := us.CreateUser(ctx, models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "secret"})
+
+	sm := services.NewSessionManager(ram.NewSessionRepository(), us, services.OpaqueTokens{}, clock.Real{}, services.SessionPolicy{
+		TTL:         time.Hour,
+		IdleTimeout: time.Hour,
+		RefreshTTL:  24 * time.Hour,
+	})
+	_, pair, _ := sm.Create(ctx, *user, models.ClientInfo{})
+
+	userRepo.failed = false
+	if _, _, err := sm.Refresh(ctx, pair.RefreshToken, models.ClientInfo{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("failed lookup: want DeadlineExceeded, have: %v", err)
+	}
+
+	// retry is not a reuse, token was not consumed
+	if _, _, err := sm.Refresh(ctx, pair.RefreshToken, models.ClientInfo{}); err != nil {
+		t.Fatalf("retry after failure: %v", err)
+	}
+}
+
+func TestSessionManagerRefreshReuse(t *testing.T) {
+	ctx := context.Background()
+	sm, user := newTestRefreshManager(t, clock.Real{})
+
+	_, stolen, _ := sm.Create(ctx, user, models.ClientInfo{})
+	other, otherPair, _ := sm.Create(ctx, user, models.ClientInfo{})
+
+	session, pair, _ := sm.Refresh(ctx, stolen.RefreshToken, models.ClientInfo{})
+
+	if _, _, err := sm.Refresh(ctx, stolen.RefreshToken, models.ClientInfo{}); !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Fatalf("reused token: want ErrInvalidCredentials, have: %v", err)
+	}
+
+	if _, err := sm.Get(ctx, session.ID, pair.AccessToken); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("family session after reuse: want ErrNotFound, have: %v", err)
+	}
+	if _, _, err := sm.Refresh(ctx, pair.RefreshToken, models.ClientInfo{}); !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Fatalf("family refresh token after reuse: want ErrInvalidCredentials, have: %v", err)
+	}
+
+	if _, err := sm.Get(ctx, other.ID, otherPair.AccessToken); err != nil {
+		t.Fatalf("session of other family was revoked: %v", err)
+	}
+}
+
+func TestSessionManagerRefreshExpiredOrRevoked(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sm, user := newTestRefreshManager(t, clk)
+
+	_, expired, _ := sm.Create(ctx, user, models.ClientInfo{})
+	clk.Advance(25 * time.Hour)
+
+	if _, _, err := sm.Refresh(ctx, expired.RefreshToken, models.ClientInfo{}); !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Fatalf("expired token: want ErrInvalidCredentials, have: %v", err)
+	}
+
+	session, loggedOut, _ := sm.Create(ctx, user, models.ClientInfo{})
+	sm.Delete(ctx, session.ID)
+
+	if _, _, err := sm.Refresh(ctx, loggedOut.RefreshToken, models.ClientInfo{}); !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Fatalf("token of deleted session: want ErrInvalidCredentials, have: %v", err)
+	}
+}
+
+func TestSessionManagerRefreshAfterAccessExpiry(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sm, user := newTestRefreshManager(t, clk)
+
+	session, pair, _ := sm.Create(ctx, user, models.ClientInfo{})
+	clk.Advance(2 * time.Hour)
+
+	if _, err := sm.Get(ctx, session.ID, pair.AccessToken); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("expired session: want ErrNotFound, have: %v", err)
+	}
+	if _, err := sm.DeleteExpired(ctx); err != nil {
+		t.Fatalf("cannot delete expired: %v", err)
+	}
+
+	newSession, newPair, err := sm.Refresh(ctx, pair.RefreshToken, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("refresh within RefreshTTL: unexpected error: %v", err)
+	}
+	if _, err := sm.Get(ctx, newSession.ID, newPair.AccessToken); err != nil {
+		t.Fatalf("new session rejected: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"rwa/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	opaqueSecretLength = 32
+	opaqueSeparator    = "."
+)
+
+var ErrBadToken = errors.New("bad token")
+
+// TokenStrategy turns session into client token and back.
+// Token only points to session, it is valid while session is stored in repository
+type TokenStrategy interface {
+	Issue(session models.Session, expiresAt time.Time) (string, error)
+	// Parse checks token format and signature and returns session id
+	Parse(token string) (string, error)
+}
+
+// OpaqueTokens are "<session id>.<secret>", secret has 256 bits of entropy
+type OpaqueTokens struct{}
+
+func (OpaqueTokens) Issue(session models.Session, _ time.Time) (string, error) {
+	return newOpaqueToken(session.ID)
+}
+
+func (OpaqueTokens) Parse(token string) (string, error) {
+	sessionId, secret, ok := strings.Cut(token, opaqueSeparator)
+	if !ok || sessionId == "" || secret == "" {
+		return "", ErrBadToken
+	}
+
+	return sessionId, nil
+}
+
+func newOpaqueToken(id string) (string, error) {
+	secret := make([]byte, opaqueSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return id + opaqueSeparator + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// JWTTokens are signed JWT with session id in jti claim
+type JWTTokens struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func NewJWTTokens(method jwt.SigningMethod, signKey, verifyKey interface{}) *JWTTokens {
+	return &JWTTokens{
+		method:    method,
+		signKey:   signKey,
+		verifyKey: verifyKey,
+	}
+}
+
+// NewJWTTokensFromKey creates HS256 tokens from raw secret or RS256 tokens from PEM private key
+func NewJWTTokensFromKey(alg string, key []byte) (*JWTTokens, error) {
+	switch alg {
+	case jwt.SigningMethodHS256.Alg():
+		if len(key) == 0 {
+			return nil, errors.New("empty HS256 secret")
+		}
+		return NewJWTTokens(jwt.SigningMethodHS256, key, key), nil
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(key)
+		if err != nil {
+			return nil, err
+		}
+		return NewJWTTokens(jwt.SigningMethodRS256, privateKey, &privateKey.PublicKey), nil
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", alg)
+	}
+}
+
+func (t *JWTTokens) Issue(session models.Session, expiresAt time.Time) (string, error) {
+	claims := jwt.StandardClaims{
+		Id:        session.ID,
+		Subject:   strconv.FormatInt(session.UserId, 10),
+		IssuedAt:  session.CreatedAt.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+
+	return jwt.NewWithClaims(t.method, claims).SignedString(t.signKey)
+}
+
+func (t *JWTTokens) Parse(token string) (string, error) {
+	claims := jwt.StandardClaims{}
+	_, err := jwt.ParseWithClaims(token, &claims, func(tok *jwt.Token) (interface{}, error) {
+		// only configured algorithm is accepted, no "none" or HS/RS confusion
+		if tok.Method.Alg() != t.method.Alg() {
+			return nil, ErrBadToken
+		}
+		return t.verifyKey, nil
+	})
+	if err != nil || claims.Id == "" {
+		return "", ErrBadToken
+	}
+
+	return claims.Id, nil
+}
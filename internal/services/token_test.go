@@ -0,0 +1,105 @@
+package services_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"rwa/internal/models"
+	"rwa/internal/repository/ram"
+	"rwa/internal/services"
+	"rwa/pkg/clock"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func newTestRSAKey(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate rsa key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestTokenStrategies(t *testing.T) {
+	ctx := context.Background()
+	hs, err := services.NewJWTTokensFromKey("HS256", []byte("secret"))
+	if err != nil {
+		t.Fatalf("HS256: %v", err)
+	}
+	rs, err := services.NewJWTTokensFromKey("RS256", newTestRSAKey(t))
+	if err != nil {
+		t.Fatalf("RS256: %v", err)
+	}
+
+	strategies := map[string]services.TokenStrategy{
+		"opaque": services.OpaqueTokens{},
+		"HS256":  hs,
+		"RS256":  rs,
+	}
+
+	for name, tokens := range strategies {
+		t.Run(name, func(t *testing.T) {
+			sesRepo := ram.NewSessionRepository()
+			sm := services.NewSessionManager(sesRepo, nil, tokens, clock.Real{}, services.SessionPolicy{TTL: time.Hour, IdleTimeout: time.Hour})
+
+			session, pair, err := sm.Create(ctx, models.User{ID: 1}, models.ClientInfo{})
+			if err != nil {
+				t.Fatalf("cannot create session: %v", err)
+			}
+
+			token := pair.AccessToken
+			sessionId, err := tokens.Parse(token)
+			if err != nil || sessionId != session.ID {
+				t.Fatalf("want session id %s, have: %s, %v", session.ID, sessionId, err)
+			}
+
+			if _, err := sm.Get(ctx, sessionId, token); err != nil {
+				t.Fatalf("valid token rejected: %v", err)
+			}
+
+			// token stays stateful, deleted session is not valid anymore
+			sm.Delete(ctx, session.ID)
+			if _, err := sm.Get(ctx, sessionId, token); !errors.Is(err, models.ErrNotFound) {
+				t.Fatalf("revoked session: want ErrNotFound, have: %v", err)
+			}
+		})
+	}
+}
+
+func TestJWTTokensRejectForeignTokens(t *testing.T) {
+	tokens, _ := services.NewJWTTokensFromKey("HS256", []byte("secret"))
+	claims := jwt.StandardClaims{Id: "session", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+	foreign := map[string]func() (string, error){
+		"other secret": func() (string, error) {
+			return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("other"))
+		},
+		"other algorithm": func() (string, error) {
+			return jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte("secret"))
+		},
+		"none algorithm": func() (string, error) {
+			return jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		},
+		"expired": func() (string, error) {
+			expired := jwt.StandardClaims{Id: "session", ExpiresAt: time.Now().Add(-time.Hour).Unix()}
+			return jwt.NewWithClaims(jwt.SigningMethodHS256, expired).SignedString([]byte("secret"))
+		},
+	}
+
+	for name, sign := range foreign {
+		token, err := sign()
+		if err != nil {
+			t.Fatalf("%s: cannot sign: %v", name, err)
+		}
+
+		if _, err := tokens.Parse(token); !errors.Is(err, services.ErrBadToken) {
+			t.Fatalf("%s: want ErrBadToken, have: %v", name, err)
+		}
+	}
+}
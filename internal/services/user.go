@@ -1,34 +1,52 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"rwa/internal/models"
 	"rwa/pkg/passwordcryptor"
 	"time"
 )
 
 type UserRepository interface {
-	GetById(id int64) (*models.User, error)
-	GetByUsername(string) (*models.User, error)
-	GetByEmail(email string) (*models.User, error)
-	Save(models.User) (int64, error)
-	Update(models.User) error
-	Delete(models.User) error
+	GetById(ctx context.Context, id int64) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Save(ctx context.Context, user models.User) (int64, error)
+	Update(ctx context.Context, user models.User) error
+	// ReplacePasswordHash changes only password hash and only if it is still oldHash,
+	// otherwise it returns models.ErrNotFound
+	ReplacePasswordHash(ctx context.Context, userId int64, oldHash, newHash string) error
+	Delete(ctx context.Context, user models.User) error
+}
+
+type FollowRepository interface {
+	Follow(ctx context.Context, followerId, followeeId int64) error
+	Unfollow(ctx context.Context, followerId, followeeId int64) error
+	IsFollowing(ctx context.Context, followerId, followeeId int64) (bool, error)
+	GetFollowees(ctx context.Context, followerId int64) ([]int64, error)
 }
 
 type UserService struct {
-	userRepo  UserRepository
-	passCrypt passwordcryptor.PasswordCryptor
+	userRepo   UserRepository
+	followRepo FollowRepository
+	passCrypt  passwordcryptor.Hasher
+	policy     IdentityPolicy
 }
 
-func NewUserService(userRepo UserRepository, passCryptor passwordcryptor.PasswordCryptor) *UserService {
+func NewUserService(userRepo UserRepository, followRepo FollowRepository, passCryptor passwordcryptor.Hasher, policy IdentityPolicy) *UserService {
 	return &UserService{
-		userRepo:  userRepo,
-		passCrypt: passCryptor,
+		userRepo:   userRepo,
+		followRepo: followRepo,
+		passCrypt:  passCryptor,
+		policy:     policy,
 	}
 }
 
-func (us *UserService) CreateUser(info models.UserCreateInfo) (*models.User, error) {
-	if err := info.Validate(); err != nil {
+func (us *UserService) CreateUser(ctx context.Context, info models.UserCreateInfo) (*models.User, error) {
+	info = us.policy.NormalizeCreate(info)
+	if err := us.policy.ValidateCreate(info); err != nil {
 		return nil, err
 	}
 
@@ -48,7 +66,7 @@ func (us *UserService) CreateUser(info models.UserCreateInfo) (*models.User, err
 		HashedPassword: hashedPass,
 	}
 
-	id, err := us.userRepo.Save(user)
+	id, err := us.userRepo.Save(ctx, user)
 	if err != nil {
 		return nil, err
 	}
@@ -58,12 +76,19 @@ func (us *UserService) CreateUser(info models.UserCreateInfo) (*models.User, err
 	return &user, nil
 }
 
-func (us *UserService) UpdateUser(user models.User, newInfo models.UserUpdateInfo) (*models.User, error) {
+func (us *UserService) UpdateUser(ctx context.Context, user models.User, newInfo models.UserUpdateInfo) (*models.User, error) {
+	newInfo = us.policy.NormalizeUpdate(newInfo)
+	if err := us.policy.ValidateUpdate(newInfo); err != nil {
+		return nil, err
+	}
+
 	updated := time.Now()
 
 	user.UpdatedAt = updated
-	if newInfo.Email != "" {
+	if newInfo.Email != "" && newInfo.Email != user.Email {
+		// new address is not confirmed yet
 		user.Email = newInfo.Email
+		user.Verified = false
 	}
 	if newInfo.Bio != "" {
 		user.Bio = newInfo.Bio
@@ -75,12 +100,39 @@ func (us *UserService) UpdateUser(user models.User, newInfo models.UserUpdateInf
 		user.Image = newInfo.Image
 	}
 
-	err := us.userRepo.Update(user)
+	err := us.userRepo.Update(ctx, user)
 	return &user, err
 }
 
-func (us *UserService) GetUserByUsername(username string) (*models.User, error) {
-	user, err := us.userRepo.GetByUsername(username)
+// ValidatePassword checks new password against policy, field is used in error messages
+func (us *UserService) ValidatePassword(field string, password string) error {
+	return us.policy.ValidatePassword(field, password)
+}
+
+// SetPassword does not validate password, stored hashes are rehashed with it too
+func (us *UserService) SetPassword(ctx context.Context, user models.User, password string) (*models.User, error) {
+	hashedPass, err := us.getPasswordHash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user.HashedPassword = hashedPass
+	user.UpdatedAt = time.Now()
+
+	err = us.userRepo.Update(ctx, user)
+	return &user, err
+}
+
+func (us *UserService) MarkVerified(ctx context.Context, user models.User) (*models.User, error) {
+	user.Verified = true
+	user.UpdatedAt = time.Now()
+
+	err := us.userRepo.Update(ctx, user)
+	return &user, err
+}
+
+func (us *UserService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	user, err := us.userRepo.GetByUsername(ctx, username)
 	if err != nil {
 		return nil, err
 	}
@@ -88,8 +140,8 @@ func (us *UserService) GetUserByUsername(username string) (*models.User, error)
 	return user, nil
 }
 
-func (us *UserService) GetByEmail(email string) (*models.User, error) {
-	user, err := us.userRepo.GetByEmail(email)
+func (us *UserService) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := us.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
@@ -97,8 +149,8 @@ func (us *UserService) GetByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
-func (us *UserService) GetUserById(id int64) (*models.User, error) {
-	user, err := us.userRepo.GetById(id)
+func (us *UserService) GetUserById(ctx context.Context, id int64) (*models.User, error) {
+	user, err := us.userRepo.GetById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -106,10 +158,75 @@ func (us *UserService) GetUserById(id int64) (*models.User, error) {
 	return user, nil
 }
 
-func (us *UserService) VerificatePassword(user models.User, password string) bool {
-	return us.passCrypt.CheckHash(password, user.HashedPassword)
+func (us *UserService) Follow(ctx context.Context, follower models.User, followee models.User) error {
+	if follower.ID == followee.ID {
+		return errors.New("cannot follow yourself")
+	}
+
+	return us.followRepo.Follow(ctx, follower.ID, followee.ID)
+}
+
+func (us *UserService) Unfollow(ctx context.Context, follower models.User, followee models.User) error {
+	return us.followRepo.Unfollow(ctx, follower.ID, followee.ID)
+}
+
+func (us *UserService) IsFollowing(ctx context.Context, follower models.User, followee models.User) (bool, error) {
+	return us.followRepo.IsFollowing(ctx, follower.ID, followee.ID)
+}
+
+func (us *UserService) GetFolloweesIds(ctx context.Context, follower models.User) ([]int64, error) {
+	return us.followRepo.GetFollowees(ctx, follower.ID)
+}
+
+// viewer may be nil for anonymous requests, then following is always false
+func (us *UserService) GetProfile(ctx context.Context, viewer *models.User, user models.User) (*models.Profile, error) {
+	profile := user.ToProfile()
+
+	if viewer != nil {
+		following, err := us.IsFollowing(ctx, *viewer, user)
+		if err != nil {
+			return nil, err
+		}
+		profile.Following = following
+	}
+
+	return &profile, nil
+}
+
+// VerificatePassword checks password and rehashes it if hash was made
+// with outdated algorithm or params
+func (us *UserService) VerificatePassword(ctx context.Context, user models.User, password string) bool {
+	ok, rehash := us.passCrypt.Verify(password, user.HashedPassword)
+	if !ok {
+		return false
+	}
+
+	if rehash {
+		// user is already verified, failed rehash only postpones upgrade
+		if err := us.rehashPassword(ctx, user, password); err != nil {
+			fmt.Println("rehash password:", err)
+		}
+	}
+
+	return true
+}
+
+// rehashPassword keeps the rest of user as is, user may be stale after slow hash check.
+// Password changed meanwhile is not overwritten
+func (us *UserService) rehashPassword(ctx context.Context, user models.User, password string) error {
+	hash, err := us.getPasswordHash(password)
+	if err != nil {
+		return err
+	}
+
+	err = us.userRepo.ReplacePasswordHash(ctx, user.ID, user.HashedPassword, hash)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+
+	return err
 }
 
 func (us *UserService) getPasswordHash(password string) (string, error) {
-	return us.passCrypt.Crypt(password)
+	return us.passCrypt.Hash(password)
 }
@@ -0,0 +1,94 @@
+package services_test
+
+import (
+	"context"
+	"rwa/internal/models"
+	"rwa/internal/repository/ram"
+	"rwa/internal/services"
+	"rwa/pkg/passwordcryptor"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserServiceRehashOnLogin(t *testing.T) {
+	ctx := context.Background()
+	userRepo := ram.NewUserRepository()
+	legacy := passwordcryptor.Bcrypt{Cost: bcrypt.MinCost}
+
+	oldService := services.NewUserService(userRepo, ram.NewFollowRepository(), legacy, services.IdentityPolicy{})
+	user, _ := oldService.CreateUser(ctx, models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "secret"})
+
+	hasher := passwordcryptor.NewChain(passwordcryptor.Argon2id{Memory: 1024, Time: 1, Threads: 1}, legacy)
+	us := services.NewUserService(userRepo, ram.NewFollowRepository(), hasher, services.IdentityPolicy{})
+
+	if us.VerificatePassword(ctx, *user, "wrong") {
+		t.Fatalf("wrong password accepted")
+	}
+	if stored, _ := us.GetUserById(ctx, user.ID); stored.HashedPassword != user.HashedPassword {
+		t.Fatalf("hash changed after wrong password")
+	}
+
+	if !us.VerificatePassword(ctx, *user, "secret") {
+		t.Fatalf("legacy hash rejected")
+	}
+
+	stored, _ := us.GetUserById(ctx, user.ID)
+	if !strings.HasPrefix(stored.HashedPassword, "$argon2id$") || !us.VerificatePassword(ctx, *stored, "secret") {
+		t.Fatalf("password is not rehashed, have: %s", stored.HashedPassword)
+	}
+}
+
+func TestUserServicePepperEnabledLater(t *testing.T) {
+	ctx := context.Background()
+	userRepo := ram.NewUserRepository()
+	argon := passwordcryptor.Argon2id{Memory: 1024, Time: 1, Threads: 1}
+
+	oldService := services.NewUserService(userRepo, ram.NewFollowRepository(), passwordcryptor.NewChain(argon), services.IdentityPolicy{})
+	user, _ := oldService.CreateUser(ctx, models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "secret"})
+
+	hasher := passwordcryptor.NewPepperedChain([]byte("pepper"), argon)
+	us := services.NewUserService(userRepo, ram.NewFollowRepository(), hasher, services.IdentityPolicy{})
+
+	if !us.VerificatePassword(ctx, *user, "secret") {
+		t.Fatalf("hash made before pepper rejected")
+	}
+
+	stored, _ := us.GetUserById(ctx, user.ID)
+	if unpeppered, _ := argon.Verify("secret", stored.HashedPassword); stored.HashedPassword == user.HashedPassword || unpeppered {
+		t.Fatalf("password is not rehashed with pepper, have: %s", stored.HashedPassword)
+	}
+	if !us.VerificatePassword(ctx, *stored, "secret") {
+		t.Fatalf("peppered hash rejected")
+	}
+}
+
+func TestUserServiceRehashKeepsNewerChanges(t *testing.T) {
+	ctx := context.Background()
+	userRepo := ram.NewUserRepository()
+	legacy := passwordcryptor.Bcrypt{Cost: bcrypt.MinCost}
+
+	oldService := services.NewUserService(userRepo, ram.NewFollowRepository(), legacy, services.IdentityPolicy{})
+	user, _ := oldService.CreateUser(ctx, models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "secret"})
+
+	hasher := passwordcryptor.NewChain(passwordcryptor.Argon2id{Memory: 1024, Time: 1, Threads: 1}, legacy)
+	us := services.NewUserService(userRepo, ram.NewFollowRepository(), hasher, services.IdentityPolicy{})
+
+	// login loaded user, then password and bio were changed before its check finished
+	stale := *user
+	changed, _ := us.SetPassword(ctx, *user, "new")
+	changed.Bio = "bio"
+	if err := userRepo.Update(ctx, *changed); err != nil {
+		t.Fatalf("cannot update user: %v", err)
+	}
+
+	if !us.VerificatePassword(ctx, stale, "secret") {
+		t.Fatalf("legacy hash rejected")
+	}
+
+	stored, _ := us.GetUserById(ctx, user.ID)
+	if stored.Bio != "bio" || !us.VerificatePassword(ctx, *stored, "new") || us.VerificatePassword(ctx, *stored, "secret") {
+		t.Fatalf("rehash overwrote newer changes: %+v", stored)
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"rwa/internal/models"
+	"rwa/pkg/clock"
+	"time"
+)
+
+type UserTokenRepository interface {
+	Get(ctx context.Context, id string) (*models.UserToken, error)
+	// Replace deletes user tokens of the token purpose and saves token in one step.
+	// If any of them is created after since, it keeps them and returns models.ErrTooManyAttempts,
+	// zero since disables the check
+	Replace(ctx context.Context, token models.UserToken, since time.Time) error
+	// Delete returns models.ErrNotFound if token is already deleted
+	Delete(ctx context.Context, id string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
+
+// NewUserTokenSweep removes expired tokens of all purposes
+func NewUserTokenSweep(repo UserTokenRepository, clk clock.Clock) Sweep {
+	return func(ctx context.Context) (int, error) {
+		return repo.DeleteExpired(ctx, clk.Now())
+	}
+}
+
+// issueUserToken replaces user tokens of the purpose with new one,
+// so only the latest mailed token is valid. Zero interval allows to issue tokens at any rate,
+// otherwise previous token has to be older than interval
+func issueUserToken(ctx context.Context, repo UserTokenRepository, user models.User, purpose models.TokenPurpose, now time.Time, ttl, interval time.Duration) (string, error) {
+	var since time.Time
+	if interval > 0 {
+		since = now.Add(-interval)
+	}
+
+	id, err := generateId()
+	if err != nil {
+		return "", err
+	}
+
+	token, err := newOpaqueToken(id)
+	if err != nil {
+		return "", err
+	}
+
+	hash := hashToken(token)
+	err = repo.Replace(ctx, models.UserToken{
+		ID:        id,
+		UserId:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hash[:],
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, since)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// useUserToken checks token and deletes it, any bad token gives models.ErrInvalidToken
+func useUserToken(ctx context.Context, repo UserTokenRepository, token string, purpose models.TokenPurpose, now time.Time) (*models.UserToken, error) {
+	id, err := OpaqueTokens{}.Parse(token)
+	if err != nil {
+		return nil, models.ErrInvalidToken
+	}
+
+	ut, err := repo.Get(ctx, id)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, models.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashToken(token)
+	if subtle.ConstantTimeCompare(hash[:], ut.TokenHash) != 1 || ut.Purpose != purpose || !now.Before(ut.ExpiresAt) {
+		return nil, models.ErrInvalidToken
+	}
+
+	err = repo.Delete(ctx, ut.ID)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, models.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ut, nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rwa/internal/models"
+	"rwa/pkg/clock"
+	"rwa/pkg/mailer"
+	"time"
+)
+
+var ErrAlreadyVerified = errors.New("email is already verified")
+
+type VerificationPolicy struct {
+	TokenTTL time.Duration
+	// ResendInterval is minimal time between two mails to the same user
+	ResendInterval time.Duration
+	// AllowUnverifiedWrites lets unverified users do everything,
+	// otherwise they can only read
+	AllowUnverifiedWrites bool
+}
+
+type VerificationService struct {
+	userService *UserService
+	tokenRepo   UserTokenRepository
+	mailer      mailer.Mailer
+	clock       clock.Clock
+	policy      VerificationPolicy
+}
+
+func NewVerificationService(us *UserService, tokenRepo UserTokenRepository, m mailer.Mailer, clk clock.Clock, policy VerificationPolicy) *VerificationService {
+	return &VerificationService{
+		userService: us,
+		tokenRepo:   tokenRepo,
+		mailer:      m,
+		clock:       clk,
+		policy:      policy,
+	}
+}
+
+// SendVerification mails new verification token, previous tokens stop working
+func (vs *VerificationService) SendVerification(ctx context.Context, user models.User) error {
+	return vs.send(ctx, user, 0)
+}
+
+// Resend is SendVerification limited to one mail per ResendInterval
+func (vs *VerificationService) Resend(ctx context.Context, user models.User) error {
+	return vs.send(ctx, user, vs.policy.ResendInterval)
+}
+
+func (vs *VerificationService) send(ctx context.Context, user models.User, interval time.Duration) error {
+	if user.Verified {
+		return ErrAlreadyVerified
+	}
+
+	token, err := issueUserToken(ctx, vs.tokenRepo, user, models.PurposeEmailVerification, vs.clock.Now(), vs.policy.TokenTTL, interval)
+	if err != nil {
+		return err
+	}
+
+	return vs.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body:    fmt.Sprintf("Use this token to confirm your email, it is valid for %s:\n\n%s", vs.policy.TokenTTL, token),
+	})
+}
+
+func (vs *VerificationService) Verify(ctx context.Context, token string) (*models.User, error) {
+	ut, err := useUserToken(ctx, vs.tokenRepo, token, models.PurposeEmailVerification, vs.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := vs.userService.GetUserById(ctx, ut.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return vs.userService.MarkVerified(ctx, *user)
+}
+
+// CanWrite tells whether user may change anything besides own account
+func (vs *VerificationService) CanWrite(user models.User) bool {
+	return user.Verified || vs.policy.AllowUnverifiedWrites
+}
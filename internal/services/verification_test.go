@@ -0,0 +1,137 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"rwa/internal/models"
+	"rwa/internal/repository/ram"
+	"rwa/internal/services"
+	"rwa/pkg/clock"
+	"rwa/pkg/mailer"
+	"rwa/pkg/passwordcryptor"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerificationService(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	mails := mailer.NewMemory()
+	us := services.NewUserService(ram.NewUserRepository(), ram.NewFollowRepository(), passwordcryptor.Bcrypt{Cost: bcrypt.MinCost}, services.IdentityPolicy{})
+	vs := services.NewVerificationService(us, ram.NewUserTokenRepository(), mails, clk, services.VerificationPolicy{
+		TokenTTL:       time.Hour,
+		ResendInterval: time.Minute,
+	})
+
+	user, _ := us.CreateUser(ctx, models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "secret"})
+	if user.Verified || vs.CanWrite(*user) {
+		t.Fatalf("new user must be unverified and read only")
+	}
+
+	lastToken := func() string {
+		msgs := mails.Messages(user.Email)
+		lines := strings.Split(msgs[len(msgs)-1].Body, "\n")
+		return lines[len(lines)-1]
+	}
+
+	vs.SendVerification(ctx, *user)
+	first := lastToken()
+
+	if err := vs.Resend(ctx, *user); !errors.Is(err, models.ErrTooManyAttempts) {
+		t.Fatalf("resend too early: want ErrTooManyAttempts, have: %v", err)
+	}
+
+	clk.Advance(2 * time.Minute)
+	if err := vs.Resend(ctx, *user); err != nil {
+		t.Fatalf("cannot resend: %v", err)
+	}
+
+	if _, err := vs.Verify(ctx, first); !errors.Is(err, models.ErrInvalidToken) {
+		t.Fatalf("replaced token: want ErrInvalidToken, have: %v", err)
+	}
+
+	verified, err := vs.Verify(ctx, lastToken())
+	if err != nil || !verified.Verified || !vs.CanWrite(*verified) {
+		t.Fatalf("cannot verify: %v", err)
+	}
+
+	if err := vs.Resend(ctx, *verified); !errors.Is(err, services.ErrAlreadyVerified) {
+		t.Fatalf("resend to verified: want ErrAlreadyVerified, have: %v", err)
+	}
+
+	// changed email has to be confirmed again
+	updated, _ := us.UpdateUser(ctx, *verified, models.UserUpdateInfo{Email: "new@example.com"})
+	if updated.Verified {
+		t.Fatalf("changed email must be unverified")
+	}
+}
+
+func TestVerificationServiceConcurrentResend(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	mails := mailer.NewMemory()
+	us := services.NewUserService(ram.NewUserRepository(), ram.NewFollowRepository(), passwordcryptor.Bcrypt{Cost: bcrypt.MinCost}, services.IdentityPolicy{})
+	vs := services.NewVerificationService(us, ram.NewUserTokenRepository(), mails, clk, services.VerificationPolicy{
+		TokenTTL:       time.Hour,
+		ResendInterval: time.Minute,
+	})
+
+	user, _ := us.CreateUser(ctx, models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "secret"})
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			vs.Resend(ctx, *user)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if n := len(mails.Messages(user.Email)); n != 1 {
+		t.Fatalf("concurrent resends: want 1 mail, have %d", n)
+	}
+}
+
+func TestUserTokenSweep(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	mails := mailer.NewMemory()
+	tokens := ram.NewUserTokenRepository()
+	us := services.NewUserService(ram.NewUserRepository(), ram.NewFollowRepository(), passwordcryptor.Bcrypt{Cost: bcrypt.MinCost}, services.IdentityPolicy{})
+	vs := services.NewVerificationService(us, tokens, mails, clk, services.VerificationPolicy{
+		TokenTTL:       time.Hour,
+		ResendInterval: time.Minute,
+	})
+	sweep := services.NewUserTokenSweep(tokens, clk)
+
+	user, _ := us.CreateUser(ctx, models.UserCreateInfo{Email: "user@example.com", Username: "user", Password: "secret"})
+	vs.SendVerification(ctx, *user)
+
+	if n, err := sweep(ctx); err != nil || n != 0 {
+		t.Fatalf("live token must be kept, have: %d, %v", n, err)
+	}
+
+	clk.Advance(time.Hour + time.Second)
+	if n, err := sweep(ctx); err != nil || n != 1 {
+		t.Fatalf("want expired token deleted, have: %d, %v", n, err)
+	}
+}
+
+func TestCreateUserRejectsInvalidEmail(t *testing.T) {
+	ctx := context.Background()
+	us := services.NewUserService(ram.NewUserRepository(), ram.NewFollowRepository(), passwordcryptor.Bcrypt{Cost: bcrypt.MinCost}, services.IdentityPolicy{})
+
+	for _, email := range []string{"plain", "User <user@example.com>", "user@"} {
+		if _, err := us.CreateUser(ctx, models.UserCreateInfo{Email: email, Username: "user", Password: "secret"}); err == nil {
+			t.Fatalf("email %q accepted", email)
+		}
+	}
+}
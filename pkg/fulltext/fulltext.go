@@ -0,0 +1,231 @@
+package fulltext
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// fieldsGap separates positions of document fields so phrases don't match across them
+const fieldsGap = 2
+
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Term is one query condition: a word, a word prefix or a phrase
+type Term struct {
+	Tokens []string
+	Prefix bool
+}
+
+type Query []Term
+
+// ParseQuery splits query into terms: "quoted text" is a phrase,
+// word* matches every word starting with prefix, other words are matched as is.
+// Document must match all terms
+func ParseQuery(q string) Query {
+	query := Query{}
+
+	for i, part := range strings.Split(q, `"`) {
+		// odd parts are inside quotes
+		if i%2 == 1 {
+			if tokens := Tokenize(part); len(tokens) != 0 {
+				query = append(query, Term{Tokens: tokens})
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			prefix := strings.HasSuffix(word, "*")
+
+			for _, t := range Tokenize(word) {
+				query = append(query, Term{Tokens: []string{t}, Prefix: prefix})
+			}
+		}
+	}
+
+	return query
+}
+
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+type occurrence struct {
+	pos    int
+	weight float64
+}
+
+// Index is inverted index from token to documents positions, it is not safe for concurrent use
+type Index struct {
+	postings map[string]map[string][]occurrence
+	docs     map[string][]string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string][]occurrence),
+		docs:     make(map[string][]string),
+	}
+}
+
+func (idx *Index) Add(docId string, fields ...Field) {
+	idx.Remove(docId)
+
+	tokens := make([]string, 0)
+	pos := 0
+	for _, f := range fields {
+		for _, t := range Tokenize(f.Text) {
+			if _, ex := idx.postings[t]; !ex {
+				idx.postings[t] = make(map[string][]occurrence)
+			}
+			if _, ex := idx.postings[t][docId]; !ex {
+				tokens = append(tokens, t)
+			}
+
+			idx.postings[t][docId] = append(idx.postings[t][docId], occurrence{pos: pos, weight: f.Weight})
+			pos++
+		}
+		pos += fieldsGap
+	}
+
+	idx.docs[docId] = tokens
+}
+
+func (idx *Index) Remove(docId string) {
+	for _, t := range idx.docs[docId] {
+		delete(idx.postings[t], docId)
+
+		if len(idx.postings[t]) == 0 {
+			delete(idx.postings, t)
+		}
+	}
+
+	delete(idx.docs, docId)
+}
+
+// Search returns relevance scores of documents matching every query term
+func (idx *Index) Search(query Query) map[string]float64 {
+	var scores map[string]float64
+
+	for _, term := range query {
+		termScores := idx.searchTerm(term)
+
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+
+		for docId := range scores {
+			s, found := termScores[docId]
+			if !found {
+				delete(scores, docId)
+				continue
+			}
+			scores[docId] += s
+		}
+	}
+
+	if scores == nil {
+		return map[string]float64{}
+	}
+
+	return scores
+}
+
+func (idx *Index) searchTerm(term Term) map[string]float64 {
+	if len(term.Tokens) == 1 {
+		if term.Prefix {
+			return idx.searchPrefix(term.Tokens[0])
+		}
+
+		return idx.searchToken(term.Tokens[0])
+	}
+
+	return idx.searchPhrase(term.Tokens)
+}
+
+func (idx *Index) searchToken(token string) map[string]float64 {
+	docs := idx.postings[token]
+	idf := idx.idf(len(docs))
+
+	scores := make(map[string]float64, len(docs))
+	for docId, occurrences := range docs {
+		scores[docId] = weightSum(occurrences) * idf
+	}
+
+	return scores
+}
+
+func (idx *Index) searchPrefix(prefix string) map[string]float64 {
+	scores := make(map[string]float64)
+
+	for token := range idx.postings {
+		if !strings.HasPrefix(token, prefix) {
+			continue
+		}
+
+		for docId, s := range idx.searchToken(token) {
+			scores[docId] += s
+		}
+	}
+
+	return scores
+}
+
+func (idx *Index) searchPhrase(tokens []string) map[string]float64 {
+	matches := make(map[string][]occurrence)
+
+	for docId, first := range idx.postings[tokens[0]] {
+		for _, o := range first {
+			if idx.phraseAt(docId, tokens[1:], o.pos+1) {
+				matches[docId] = append(matches[docId], o)
+			}
+		}
+	}
+
+	idf := idx.idf(len(matches))
+
+	scores := make(map[string]float64, len(matches))
+	for docId, occurrences := range matches {
+		// phrase is worth as much as all its words
+		scores[docId] = weightSum(occurrences) * idf * float64(len(tokens))
+	}
+
+	return scores
+}
+
+func (idx *Index) phraseAt(docId string, tokens []string, pos int) bool {
+	for i, t := range tokens {
+		found := false
+		for _, o := range idx.postings[t][docId] {
+			if o.pos == pos+i {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (idx *Index) idf(docsWithTerm int) float64 {
+	return math.Log(1 + float64(len(idx.docs))/float64(1+docsWithTerm))
+}
+
+func weightSum(occurrences []occurrence) float64 {
+	sum := 0.0
+	for _, o := range occurrences {
+		sum += o.weight
+	}
+
+	return sum
+}
@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTP sends plain text mail through smtp server
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTP creates mailer for server at addr ("host:port"), auth is skipped for empty username
+func NewSMTP(addr, from, username, password string) *SMTP {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTP{
+		addr: addr,
+		from: from,
+		auth: auth,
+	}
+}
+
+func (m *SMTP) Send(msg Message) error {
+	data := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", m.from, msg.To, msg.Subject, msg.Body)
+
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, []byte(data))
+}
+
+// Memory keeps messages instead of sending, for tests and local runs
+type Memory struct {
+	messages []Message
+	mu       *sync.Mutex
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		messages: make([]Message, 0),
+		mu:       &sync.Mutex{},
+	}
+}
+
+func (m *Memory) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, msg)
+
+	return nil
+}
+
+// Messages returns all messages sent to address
+func (m *Memory) Messages(to string) []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make([]Message, 0)
+	for _, msg := range m.messages {
+		if msg.To == to {
+			res = append(res, msg)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,58 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrLockTimeout = errors.New("timeout waiting for migrations lock")
+
+// MySQLLock is named server lock, it is released by server if connection is lost
+type MySQLLock struct {
+	Name    string
+	Timeout time.Duration
+}
+
+func (l MySQLLock) Lock(ctx context.Context, conn *sql.Conn) error {
+	var acquired sql.NullInt64
+
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", l.Name, int(l.Timeout.Seconds())).Scan(&acquired)
+	if err != nil {
+		return err
+	}
+	if acquired.Int64 != 1 {
+		return ErrLockTimeout
+	}
+
+	return nil
+}
+
+// Unlock doesn't undo anything: DDL statements are committed by MySQL at once
+func (l MySQLLock) Unlock(ctx context.Context, conn *sql.Conn, _ error) error {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", l.Name)
+
+	return err
+}
+
+// SQLiteLock runs the whole run in write transaction, so failed run changes nothing.
+// Other instances wait for it up to busy timeout of connection
+type SQLiteLock struct{}
+
+func (SQLiteLock) Lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+
+	return err
+}
+
+func (SQLiteLock) Unlock(ctx context.Context, conn *sql.Conn, runErr error) error {
+	if runErr != nil {
+		_, err := conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+
+	_, err := conn.ExecContext(ctx, "COMMIT")
+
+	return err
+}
@@ -0,0 +1,164 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"rwa/pkg/migrate"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var testMigrations = fstest.MapFS{
+	"0002_posts.up.sql":   {Data: []byte("CREATE TABLE posts (id INTEGER);\nINSERT INTO posts VALUES (1);\n")},
+	"0002_posts.down.sql": {Data: []byte("DROP TABLE posts;\n")},
+	"0001_users.up.sql":   {Data: []byte("-- first table\nCREATE TABLE users (\n    id INTEGER\n);\n")},
+	"0001_users.down.sql": {Data: []byte("DROP TABLE users;\n")},
+	"README.md":           {Data: []byte("not a migration")},
+}
+
+func openDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("cannot open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func newMigrator(t *testing.T, db *sql.DB, fsys fstest.MapFS) *migrate.Migrator {
+	t.Helper()
+
+	m, err := migrate.New(db, fsys, migrate.SQLiteLock{})
+	if err != nil {
+		t.Fatalf("cannot load migrations: %v", err)
+	}
+
+	return m
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := migrate.Load(testMigrations)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "users" || migrations[1].Version != 2 {
+		t.Fatalf("want users and posts migrations, have: %+v", migrations)
+	}
+
+	_, err = migrate.Load(fstest.MapFS{"0001_users.up.sql": {Data: []byte("CREATE TABLE users (id INTEGER);")}})
+	if err == nil {
+		t.Errorf("migration without down file: want error")
+	}
+
+	_, err = migrate.Load(fstest.MapFS{})
+	if err != migrate.ErrNoMigrations {
+		t.Errorf("empty dir: want ErrNoMigrations, have: %v", err)
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t, filepath.Join(t.TempDir(), "test.db"))
+	m := newMigrator(t, db, testMigrations)
+
+	applied, err := m.Up(ctx)
+	if err != nil || applied != 2 {
+		t.Fatalf("Up: want 2 applied, have: %d, %v", applied, err)
+	}
+
+	applied, err = m.Up(ctx)
+	if err != nil || applied != 0 {
+		t.Fatalf("second Up: want nothing applied, have: %d, %v", applied, err)
+	}
+
+	rolledBack, err := m.Down(ctx, 1)
+	if err != nil || rolledBack != 1 {
+		t.Fatalf("Down: want 1 rolled back, have: %d, %v", rolledBack, err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("Status: want 2 migrations, have: %v, %v", statuses, err)
+	}
+	if !statuses[0].Applied || statuses[0].AppliedAt.IsZero() || statuses[1].Applied {
+		t.Errorf("Status: want only users applied, have: %+v", statuses)
+	}
+
+	if _, err := db.Exec("SELECT * FROM posts"); err == nil {
+		t.Errorf("posts table is not dropped")
+	}
+	if _, err := db.Exec("SELECT * FROM users"); err != nil {
+		t.Errorf("users table is dropped: %v", err)
+	}
+}
+
+func TestFailedRunChangesNothing(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t, filepath.Join(t.TempDir(), "test.db"))
+
+	broken := fstest.MapFS{
+		"0001_users.up.sql":   testMigrations["0001_users.up.sql"],
+		"0001_users.down.sql": testMigrations["0001_users.down.sql"],
+		"0002_bad.up.sql":     {Data: []byte("CREATE TABLE posts (id INTEGER);\nNOT SQL AT ALL;\n")},
+		"0002_bad.down.sql":   {Data: []byte("DROP TABLE posts;\n")},
+	}
+
+	if _, err := newMigrator(t, db, broken).Up(ctx); err == nil {
+		t.Fatalf("Up: want error")
+	}
+
+	statuses, err := newMigrator(t, db, testMigrations).Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: unexpected error: %v", err)
+	}
+	for _, s := range statuses {
+		if s.Applied {
+			t.Errorf("migration %d is applied after failed run", s.Version)
+		}
+	}
+
+	if _, err := db.Exec("SELECT * FROM users"); err == nil {
+		t.Errorf("users table is kept after failed run")
+	}
+}
+
+func TestConcurrentUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total int
+	)
+
+	// every instance has its own pool as separate processes would
+	for i := 0; i < 4; i++ {
+		m := newMigrator(t, openDB(t, path), testMigrations)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			applied, err := m.Up(context.Background())
+			if err != nil {
+				t.Errorf("Up: unexpected error: %v", err)
+			}
+
+			mu.Lock()
+			total += applied
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if total != 2 {
+		t.Errorf("want every migration applied once, have %d applications", total)
+	}
+}
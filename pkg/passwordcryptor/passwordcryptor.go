@@ -1,18 +1,320 @@
 package passwordcryptor
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
-const hashCost = 12
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
 
-type PasswordCryptor struct {
+const (
+	DefaultBcryptCost = 12
+
+	saltLength = 16
+	keyLength  = 32
+)
+
+var ErrBadHash = errors.New("malformed password hash")
+
+// Hasher makes PHC formatted hashes "$<id>$<params>$<salt>$<hash>",
+// bcrypt keeps its own modular crypt format
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify is false for wrong password and for hashes of other algorithms,
+	// needsRehash tells whether verified hash has to be remade by Hash
+	Verify(password, hash string) (ok bool, needsRehash bool)
+	// NeedsRehash is true for hashes of other algorithms or with other params
+	NeedsRehash(hash string) bool
+}
+
+// Chain hashes with primary hasher and verifies hashes of all of them,
+// hashes not made by primary need rehash
+type Chain struct {
+	primary Hasher
+	legacy  []Hasher
+}
+
+func NewChain(primary Hasher, legacy ...Hasher) *Chain {
+	return &Chain{
+		primary: primary,
+		legacy:  legacy,
+	}
+}
+
+func (c *Chain) Hash(password string) (string, error) {
+	return c.primary.Hash(password)
+}
+
+// Verify reports rehash for all hashes verified by legacy hashers. Unlike NeedsRehash
+// it catches legacy hashes in primary format, like ones made before pepper was set
+func (c *Chain) Verify(password, hash string) (bool, bool) {
+	if ok, rehash := c.primary.Verify(password, hash); ok {
+		return true, rehash
+	}
+
+	for _, h := range c.legacy {
+		if ok, _ := h.Verify(password, hash); ok {
+			return true, true
+		}
+	}
+
+	return false, false
+}
+
+func (c *Chain) NeedsRehash(hash string) bool {
+	return c.primary.NeedsRehash(hash)
+}
+
+// Peppered mixes server side secret into password with HMAC-SHA256,
+// changing pepper invalidates all stored hashes
+type Peppered struct {
+	Hasher
+	pepper []byte
+}
+
+func NewPeppered(h Hasher, pepper []byte) *Peppered {
+	return &Peppered{
+		Hasher: h,
+		pepper: pepper,
+	}
+}
+
+// NewPepperedChain peppers new hashes and still verifies hashes made without pepper,
+// Verify reports them for rehash, so enabling pepper upgrades passwords on login
+func NewPepperedChain(pepper []byte, primary Hasher, legacy ...Hasher) *Chain {
+	all := make([]Hasher, 0, 2*len(legacy)+1)
+	for _, h := range legacy {
+		all = append(all, NewPeppered(h, pepper))
+	}
+	all = append(all, primary)
+	all = append(all, legacy...)
+
+	return NewChain(NewPeppered(primary, pepper), all...)
 }
 
-func (pc PasswordCryptor) Crypt(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), hashCost)
+func (p *Peppered) Hash(password string) (string, error) {
+	return p.Hasher.Hash(p.mix(password))
+}
+
+func (p *Peppered) Verify(password, hash string) (bool, bool) {
+	return p.Hasher.Verify(p.mix(password), hash)
+}
+
+// mix output is short printable string, so bcrypt 72 bytes limit is not hit
+func (p *Peppered) mix(password string) string {
+	mac := hmac.New(sha256.New, p.pepper)
+	mac.Write([]byte(password))
+
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
 
 	return string(hash), err
 }
 
-func (pc PasswordCryptor) CheckHash(password, hash string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(password), []byte(hash)) == nil
+func (b Bcrypt) Verify(password, hash string) (bool, bool) {
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false, false
+	}
+
+	return true, b.NeedsRehash(hash)
+}
+
+func (b Bcrypt) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+
+	return err != nil || cost != b.Cost
+}
+
+// Argon2id params are memory in KiB, iterations and parallelism
+type Argon2id struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+// NewArgon2id uses params recommended in RFC 9106 for constrained memory
+func NewArgon2id() Argon2id {
+	return Argon2id{Memory: 64 * 1024, Time: 3, Threads: 4}
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, keyLength)
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", a.Memory, a.Time, a.Threads)
+
+	return encodePHC("argon2id", "v=19$"+params, salt, key), nil
+}
+
+func (a Argon2id) Verify(password, hash string) (bool, bool) {
+	hashed, params, err := a.decode(hash)
+	if err != nil {
+		return false, false
+	}
+
+	key := argon2.IDKey([]byte(password), hashed.salt, params.Time, params.Memory, params.Threads, uint32(len(hashed.key)))
+	if subtle.ConstantTimeCompare(key, hashed.key) != 1 {
+		return false, false
+	}
+
+	return true, params != a
+}
+
+func (a Argon2id) NeedsRehash(hash string) bool {
+	_, params, err := a.decode(hash)
+
+	return err != nil || params != a
+}
+
+func (a Argon2id) decode(hash string) (*phcHash, Argon2id, error) {
+	hashed, err := decodePHC(hash, "argon2id")
+	if err != nil {
+		return nil, Argon2id{}, err
+	}
+
+	// version goes before params
+	version, params, ok := strings.Cut(hashed.params, "$")
+	if !ok || version != fmt.Sprintf("v=%d", argon2.Version) {
+		return nil, Argon2id{}, ErrBadHash
+	}
+
+	res := Argon2id{}
+	_, err = fmt.Sscanf(params, "m=%d,t=%d,p=%d", &res.Memory, &res.Time, &res.Threads)
+	if err != nil || res.Time == 0 || res.Threads == 0 {
+		return nil, Argon2id{}, ErrBadHash
+	}
+
+	return hashed, res, nil
+}
+
+// Scrypt params are log2 of cost N, block size and parallelism
+type Scrypt struct {
+	LogN uint8
+	R    int
+	P    int
+}
+
+// NewScrypt uses params recommended for interactive logins
+func NewScrypt() Scrypt {
+	return Scrypt{LogN: 15, R: 8, P: 1}
+}
+
+func (s Scrypt) Hash(password string) (string, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, 1<<s.LogN, s.R, s.P, keyLength)
+	if err != nil {
+		return "", err
+	}
+
+	return encodePHC("scrypt", fmt.Sprintf("ln=%d,r=%d,p=%d", s.LogN, s.R, s.P), salt, key), nil
+}
+
+func (s Scrypt) Verify(password, hash string) (bool, bool) {
+	hashed, params, err := s.decode(hash)
+	if err != nil {
+		return false, false
+	}
+
+	key, err := scrypt.Key([]byte(password), hashed.salt, 1<<params.LogN, params.R, params.P, len(hashed.key))
+	if err != nil || subtle.ConstantTimeCompare(key, hashed.key) != 1 {
+		return false, false
+	}
+
+	return true, params != s
+}
+
+func (s Scrypt) NeedsRehash(hash string) bool {
+	_, params, err := s.decode(hash)
+
+	return err != nil || params != s
+}
+
+func (s Scrypt) decode(hash string) (*phcHash, Scrypt, error) {
+	hashed, err := decodePHC(hash, "scrypt")
+	if err != nil {
+		return nil, Scrypt{}, err
+	}
+
+	res := Scrypt{}
+	_, err = fmt.Sscanf(hashed.params, "ln=%d,r=%d,p=%d", &res.LogN, &res.R, &res.P)
+	if err != nil || res.LogN == 0 || res.LogN > 31 {
+		return nil, Scrypt{}, ErrBadHash
+	}
+
+	return hashed, res, nil
+}
+
+type phcHash struct {
+	params string
+	salt   []byte
+	key    []byte
+}
+
+func encodePHC(id string, params string, salt, key []byte) string {
+	b64 := base64.RawStdEncoding
+
+	return "$" + id + "$" + params + "$" + b64.EncodeToString(salt) + "$" + b64.EncodeToString(key)
+}
+
+func decodePHC(hash string, id string) (*phcHash, error) {
+	prefix := "$" + id + "$"
+	if !strings.HasPrefix(hash, prefix) {
+		return nil, ErrBadHash
+	}
+
+	// params may contain "$" themselves, salt and key are the last two parts
+	rest := hash[len(prefix):]
+	i := strings.LastIndex(rest, "$")
+	if i < 0 {
+		return nil, ErrBadHash
+	}
+	j := strings.LastIndex(rest[:i], "$")
+	if j < 0 {
+		return nil, ErrBadHash
+	}
+
+	b64 := base64.RawStdEncoding
+	salt, err := b64.DecodeString(rest[j+1 : i])
+	if err != nil {
+		return nil, ErrBadHash
+	}
+	key, err := b64.DecodeString(rest[i+1:])
+	if err != nil || len(key) == 0 {
+		return nil, ErrBadHash
+	}
+
+	return &phcHash{
+		params: rest[:j],
+		salt:   salt,
+		key:    key,
+	}, nil
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltLength)
+	_, err := rand.Read(salt)
+
+	return salt, err
 }
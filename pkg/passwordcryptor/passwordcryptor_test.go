@@ -0,0 +1,129 @@
+package passwordcryptor_test
+
+import (
+	"rwa/pkg/passwordcryptor"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testHashers = map[string]passwordcryptor.Hasher{
+	"bcrypt":   passwordcryptor.Bcrypt{Cost: bcrypt.MinCost},
+	"argon2id": passwordcryptor.Argon2id{Memory: 1024, Time: 1, Threads: 1},
+	"scrypt":   passwordcryptor.Scrypt{LogN: 4, R: 8, P: 1},
+}
+
+// verified drops rehash flag
+func verified(h passwordcryptor.Hasher, password, hash string) bool {
+	ok, _ := h.Verify(password, hash)
+
+	return ok
+}
+
+func TestHashers(t *testing.T) {
+	for name, h := range testHashers {
+		hash, err := h.Hash("secret")
+		if err != nil {
+			t.Fatalf("%s: cannot hash: %v", name, err)
+		}
+
+		if name != "bcrypt" && !strings.HasPrefix(hash, "$"+name+"$") {
+			t.Fatalf("%s: want PHC string, have: %s", name, hash)
+		}
+		if ok, rehash := h.Verify("secret", hash); !ok || rehash || verified(h, "wrong", hash) {
+			t.Fatalf("%s: verify failed for %s", name, hash)
+		}
+		if h.NeedsRehash(hash) {
+			t.Fatalf("%s: fresh hash needs rehash", name)
+		}
+
+		for other, oh := range testHashers {
+			if other != name && (verified(oh, "secret", hash) || !oh.NeedsRehash(hash)) {
+				t.Fatalf("%s hash is accepted by %s", name, other)
+			}
+		}
+	}
+}
+
+func TestHashersNeedRehashForOtherParams(t *testing.T) {
+	old := map[string]passwordcryptor.Hasher{
+		"bcrypt":   passwordcryptor.Bcrypt{Cost: bcrypt.MinCost + 1},
+		"argon2id": passwordcryptor.Argon2id{Memory: 1024, Time: 2, Threads: 1},
+		"scrypt":   passwordcryptor.Scrypt{LogN: 5, R: 8, P: 1},
+	}
+
+	for name, h := range old {
+		hash, _ := h.Hash("secret")
+		ok, rehash := testHashers[name].Verify("secret", hash)
+		if !ok || !rehash || !testHashers[name].NeedsRehash(hash) {
+			t.Fatalf("%s: hash with other params must verify and need rehash", name)
+		}
+	}
+}
+
+func TestChainAndPepper(t *testing.T) {
+	legacy := testHashers["bcrypt"]
+	chain := passwordcryptor.NewChain(testHashers["argon2id"], legacy)
+
+	oldHash, _ := legacy.Hash("secret")
+	if ok, rehash := chain.Verify("secret", oldHash); !ok || !rehash || !chain.NeedsRehash(oldHash) {
+		t.Fatalf("legacy hash must verify and need rehash")
+	}
+
+	peppered := passwordcryptor.NewPeppered(chain, []byte("pepper"))
+	hash, _ := peppered.Hash("secret")
+	if ok, rehash := peppered.Verify("secret", hash); !ok || rehash || peppered.NeedsRehash(hash) {
+		t.Fatalf("peppered hash failed")
+	}
+
+	if verified(chain, "secret", hash) || verified(passwordcryptor.NewPeppered(chain, []byte("other")), "secret", hash) {
+		t.Fatalf("peppered hash verified without right pepper")
+	}
+}
+
+func TestPepperedReportsRehash(t *testing.T) {
+	old := passwordcryptor.NewPeppered(passwordcryptor.Argon2id{Memory: 1024, Time: 2, Threads: 1}, []byte("pepper"))
+	hash, _ := old.Hash("secret")
+
+	// wrapper is not a chain, rehash comes from wrapped hasher
+	peppered := passwordcryptor.NewPeppered(testHashers["argon2id"], []byte("pepper"))
+	if ok, rehash := peppered.Verify("secret", hash); !ok || !rehash {
+		t.Fatalf("hash with other params: want verified and rehash, have: %v, %v", ok, rehash)
+	}
+}
+
+func TestPepperEnabledLater(t *testing.T) {
+	primary, legacy := testHashers["argon2id"], testHashers["bcrypt"]
+
+	unpeppered, _ := passwordcryptor.NewChain(primary, legacy).Hash("secret")
+	oldLegacy, _ := legacy.Hash("secret")
+
+	chain := passwordcryptor.NewPepperedChain([]byte("pepper"), primary, legacy)
+	for _, hash := range []string{unpeppered, oldLegacy} {
+		if ok, rehash := chain.Verify("secret", hash); !ok || !rehash {
+			t.Fatalf("hash made without pepper: want verified and rehash, have: %v, %v", ok, rehash)
+		}
+		if verified(chain, "wrong", hash) {
+			t.Fatalf("wrong password verified")
+		}
+	}
+
+	peppered, _ := chain.Hash("secret")
+	if ok, rehash := chain.Verify("secret", peppered); !ok || rehash {
+		t.Fatalf("peppered hash: want verified without rehash, have: %v, %v", ok, rehash)
+	}
+	if verified(primary, "secret", peppered) {
+		t.Fatalf("new hash is not peppered")
+	}
+}
+
+func TestMalformedHashes(t *testing.T) {
+	for _, hash := range []string{"", "$argon2id$", "$argon2id$v=19$m=1,t=0,p=1$c2FsdA$a2V5", "$scrypt$ln=99,r=8,p=1$c2FsdA$a2V5", "$scrypt$ln=4,r=8,p=1$!!$a2V5"} {
+		for name, h := range testHashers {
+			if verified(h, "secret", hash) {
+				t.Fatalf("%s: malformed hash %q verified", name, hash)
+			}
+		}
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"rwa/cmd/config"
 	"rwa/internal/realworld"
 	"strings"
 	"testing"
@@ -58,6 +60,36 @@ type TestArticle struct {
 	UpdatedAt      FakeTime    `json:"updatedAt"`
 }
 
+type TestArticleTitle struct {
+	Title string `json:"title"`
+}
+
+type TestTagCount struct {
+	Tag           string `json:"tag"`
+	ArticlesCount int    `json:"articlesCount"`
+}
+
+type TestComment struct {
+	ID        int64    `json:"id" testdiff:"ignore"`
+	Body      string   `json:"body"`
+	CreatedAt FakeTime `json:"createdAt"`
+	UpdatedAt FakeTime `json:"updatedAt"`
+}
+
+type TestSession struct {
+	ID         string   `json:"id" testdiff:"ignore"`
+	CreatedAt  FakeTime `json:"createdAt"`
+	LastSeenAt FakeTime `json:"lastSeenAt"`
+	UserAgent  string   `json:"userAgent"`
+	IP         string   `json:"ip"`
+	Current    bool     `json:"current"`
+}
+
+type TestTokenPair struct {
+	Token        string `json:"token" testdiff:"ignore"`
+	RefreshToken string `json:"refreshToken" testdiff:"ignore"`
+}
+
 func strP(in string) *string {
 	return &in
 }
@@ -85,11 +117,30 @@ func WeirdMagicClone(in interface{}) interface{} {
 }
 
 func TestApp(t *testing.T) {
+	app := realworld.GetApp()
+	defer app.Close()
+
+	testApp(t, app)
+}
+
+// TestAppSQLite runs the same scenario with users, sessions and articles in sqlite
+func TestAppSQLite(t *testing.T) {
+	cfg := config.InitConfig()
+	cfg.Storage.Backend = config.StorageSQLite
+	cfg.Storage.SQLitePath = filepath.Join(t.TempDir(), "rwa.db")
+	cfg.Storage.AutoMigrate = true
+
+	app := realworld.NewApp(cfg)
+	defer app.Close()
+
+	testApp(t, app)
+}
+
+func testApp(t *testing.T, app http.Handler) {
 	rand.Seed(time.Now().UnixNano())
 
 	var (
-		app = realworld.GetApp()
-		ts  = httptest.NewServer(app)
+		ts = httptest.NewServer(app)
 
 		// username = RandStringRunes(16)
 		username = "golang"
@@ -97,6 +148,7 @@ func TestApp(t *testing.T) {
 		apiurl   = ts.URL + "/api"
 		password = "love"
 	)
+	defer ts.Close()
 
 	tplParams := map[string]string{
 		"APIURL":   apiurl,
@@ -363,15 +415,6 @@ func TestApp(t *testing.T) {
 					ArticlesCount int           `json:"articlesCount"`
 				}{
 					Articles: []TestArticle{
-						TestArticle{
-							Slug:        tplParams["slug1"],
-							Body:        "Any ideas how to write some intermidiate layer atop collection?",
-							Title:       "How to write golang tests",
-							Description: "I have problem with mondodb mocking",
-							CreatedAt:   FakeTime{true},
-							UpdatedAt:   FakeTime{true},
-							TagList:     []string{"golang", "testing", "gomock"},
-						},
 						TestArticle{
 							Slug:        tplParams["slug2"],
 							Body:        "Will we use JWT-tokens in homework?",
@@ -381,6 +424,15 @@ func TestApp(t *testing.T) {
 							UpdatedAt:   FakeTime{true},
 							TagList:     []string{"halflife3", "coursera"},
 						},
+						TestArticle{
+							Slug:        tplParams["slug1"],
+							Body:        "Any ideas how to write some intermidiate layer atop collection?",
+							Title:       "How to write golang tests",
+							Description: "I have problem with mondodb mocking",
+							CreatedAt:   FakeTime{true},
+							UpdatedAt:   FakeTime{true},
+							TagList:     []string{"golang", "testing", "gomock"},
+						},
 					},
 					ArticlesCount: 2,
 				}
@@ -446,6 +498,1678 @@ func TestApp(t *testing.T) {
 			After:  nil,
 		},
 
+		&ApiTestCase{
+			Name:           "Articles - Get by slug",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug2}}",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Article TestArticle
+				}{
+					Article: TestArticle{
+						Body:        "Will we use JWT-tokens in homework?",
+						Title:       "What will be released first, Half-Life 3 or 3-rd part of golang course?",
+						Description: "Who knows topics in new course?",
+						CreatedAt:   FakeTime{true},
+						UpdatedAt:   FakeTime{true},
+						TagList:     []string{"halflife3", "coursera"},
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Articles - Get by unknown slug",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/unknown-slug",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Articles - Update Article - Not author",
+			Method:         "PUT",
+			Body:           `{"article":{"title":"Stolen article"}}`,
+			URL:            "{{APIURL}}/articles/{{slug1}}",
+			TokenName:      "token2",
+			ResponseStatus: 403,
+		},
+		&ApiTestCase{
+			Name:           "Articles - Update Article - Require Auth",
+			Method:         "PUT",
+			Body:           `{"article":{"title":"Anonymous article"}}`,
+			URL:            "{{APIURL}}/articles/{{slug1}}",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Articles - Update Article - Change slug",
+			Method:         "PUT",
+			Body:           `{"article":{"description":"I have problem with mongodb mocking", "slug":"how-to-write-golang-tests-updated"}}`,
+			URL:            "{{APIURL}}/articles/{{slug1}}",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Article TestArticle
+				}{
+					Article: TestArticle{
+						Body:        "Any ideas how to write some intermidiate layer atop collection?",
+						Title:       "How to write golang tests",
+						Description: "I have problem with mongodb mocking",
+						CreatedAt:   FakeTime{true},
+						UpdatedAt:   FakeTime{true},
+						TagList:     []string{"golang", "testing", "gomock"},
+					},
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "Article.Slug")
+				if err != nil {
+					return err
+				}
+				if val.String() != "how-to-write-golang-tests-updated" {
+					return fmt.Errorf("slug not changed: %s", val.String())
+				}
+				tplParams["slug1_old"] = tplParams["slug1"]
+				tplParams["slug1"] = val.String()
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Articles - Get by old slug after update",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug1_old}}",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Articles - Delete Article - Not author",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug1}}",
+			TokenName:      "token2",
+			ResponseStatus: 403,
+		},
+		&ApiTestCase{
+			Name:           "Articles - Delete Article",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug1}}",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Articles - Get by slug after delete",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug1}}",
+			ResponseStatus: 404,
+		},
+
+		&ApiTestCase{
+			Name:           "Favorites - Favorite Article",
+			Method:         "POST",
+			URL:            "{{APIURL}}/articles/{{slug2}}/favorite",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Article TestArticle
+				}{
+					Article: TestArticle{
+						Slug:           tplParams["slug2"],
+						Body:           "Will we use JWT-tokens in homework?",
+						Title:          "What will be released first, Half-Life 3 or 3-rd part of golang course?",
+						Description:    "Who knows topics in new course?",
+						CreatedAt:      FakeTime{true},
+						UpdatedAt:      FakeTime{true},
+						TagList:        []string{"halflife3", "coursera"},
+						Favorited:      true,
+						FavoritesCount: 1,
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Favorites - Favorite Article - Require Auth",
+			Method:         "POST",
+			URL:            "{{APIURL}}/articles/{{slug2}}/favorite",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Favorites - Get Article - Anonymous",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug2}}",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Article TestArticle
+				}{
+					Article: TestArticle{
+						Slug:           tplParams["slug2"],
+						Body:           "Will we use JWT-tokens in homework?",
+						Title:          "What will be released first, Half-Life 3 or 3-rd part of golang course?",
+						Description:    "Who knows topics in new course?",
+						CreatedAt:      FakeTime{true},
+						UpdatedAt:      FakeTime{true},
+						TagList:        []string{"halflife3", "coursera"},
+						Favorited:      false,
+						FavoritesCount: 1,
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Favorites - Get Article - Viewer favorited",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug2}}",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Article TestArticle
+				}{
+					Article: TestArticle{
+						Slug:           tplParams["slug2"],
+						Body:           "Will we use JWT-tokens in homework?",
+						Title:          "What will be released first, Half-Life 3 or 3-rd part of golang course?",
+						Description:    "Who knows topics in new course?",
+						CreatedAt:      FakeTime{true},
+						UpdatedAt:      FakeTime{true},
+						TagList:        []string{"halflife3", "coursera"},
+						Favorited:      true,
+						FavoritesCount: 1,
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Favorites - Articles favorited by user",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?favorited={{USERNAME}}",
+			TokenName:      "token2",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticle `json:"articles"`
+					ArticlesCount int           `json:"articlesCount"`
+				}{
+					Articles: []TestArticle{
+						TestArticle{
+							Slug:           tplParams["slug2"],
+							Body:           "Will we use JWT-tokens in homework?",
+							Title:          "What will be released first, Half-Life 3 or 3-rd part of golang course?",
+							Description:    "Who knows topics in new course?",
+							CreatedAt:      FakeTime{true},
+							UpdatedAt:      FakeTime{true},
+							TagList:        []string{"halflife3", "coursera"},
+							Favorited:      false,
+							FavoritesCount: 1,
+						},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Favorites - Unfavorite Article",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug2}}/favorite",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Article TestArticle
+				}{
+					Article: TestArticle{
+						Slug:           tplParams["slug2"],
+						Body:           "Will we use JWT-tokens in homework?",
+						Title:          "What will be released first, Half-Life 3 or 3-rd part of golang course?",
+						Description:    "Who knows topics in new course?",
+						CreatedAt:      FakeTime{true},
+						UpdatedAt:      FakeTime{true},
+						TagList:        []string{"halflife3", "coursera"},
+						Favorited:      false,
+						FavoritesCount: 0,
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Favorites - Articles favorited by user after unfavorite",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?favorited={{USERNAME}}",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticle `json:"articles"`
+					ArticlesCount int           `json:"articlesCount"`
+				}{
+					Articles:      []TestArticle{},
+					ArticlesCount: 0,
+				}
+			},
+		},
+
+		&ApiTestCase{
+			Name:           "Comments - Create Article for comments",
+			Method:         "POST",
+			Body:           `{"article":{"title":"Comments playground", "description":"Say something", "body":"Comments are welcome"}}`,
+			URL:            "{{APIURL}}/articles",
+			TokenName:      "token1",
+			ResponseStatus: 201,
+			Before: func() {
+				tplParams["slug3"] = "comments-playground"
+			},
+		},
+		&ApiTestCase{
+			Name:           "Comments - Add Comment - Require Auth",
+			Method:         "POST",
+			Body:           `{"comment":{"body":"Anonymous comment"}}`,
+			URL:            "{{APIURL}}/articles/{{slug3}}/comments",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Comments - Add Comment - Second user",
+			Method:         "POST",
+			Body:           `{"comment":{"body":"First!"}}`,
+			URL:            "{{APIURL}}/articles/{{slug3}}/comments",
+			TokenName:      "token2",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Comment TestComment `json:"comment"`
+				}{
+					Comment: TestComment{
+						Body:      "First!",
+						CreatedAt: FakeTime{true},
+						UpdatedAt: FakeTime{true},
+					},
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "Comment.ID")
+				if err != nil {
+					return err
+				}
+				tplParams["comment1"] = fmt.Sprint(val.Int())
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Comments - Add Comment - First user",
+			Method:         "POST",
+			Body:           `{"comment":{"body":"Thanks"}}`,
+			URL:            "{{APIURL}}/articles/{{slug3}}/comments",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Comments - List Comments",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug3}}/comments",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Comments []TestComment `json:"comments"`
+				}{
+					Comments: []TestComment{
+						TestComment{Body: "First!", CreatedAt: FakeTime{true}, UpdatedAt: FakeTime{true}},
+						TestComment{Body: "Thanks", CreatedAt: FakeTime{true}, UpdatedAt: FakeTime{true}},
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Comments - List Comments - Author is public profile",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug3}}/comments",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Comments []TestComment `json:"comments"`
+				}{
+					Comments: []TestComment{
+						TestComment{Body: "First!", CreatedAt: FakeTime{true}, UpdatedAt: FakeTime{true}},
+						TestComment{Body: "Thanks", CreatedAt: FakeTime{true}, UpdatedAt: FakeTime{true}},
+					},
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				var res struct {
+					Comments []struct {
+						Author map[string]interface{} `json:"author"`
+					} `json:"comments"`
+				}
+				if err := json.Unmarshal(body, &res); err != nil {
+					return err
+				}
+
+				for _, c := range res.Comments {
+					if _, leaked := c.Author["email"]; leaked {
+						return fmt.Errorf("comment author has email: %v", c.Author)
+					}
+					if _, ok := c.Author["following"]; !ok {
+						return fmt.Errorf("comment author is not a profile: %v", c.Author)
+					}
+				}
+				if len(res.Comments) == 0 || res.Comments[0].Author["username"] != tplParams["USERNAME2"] {
+					return fmt.Errorf("want first comment by %s, have: %s", tplParams["USERNAME2"], body)
+				}
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Comments - Delete Comment - Not author",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug3}}/comments/{{comment1}}",
+			TokenName:      "token1",
+			ResponseStatus: 403,
+		},
+		&ApiTestCase{
+			Name:           "Comments - Delete Comment",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug3}}/comments/{{comment1}}",
+			TokenName:      "token2",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Comments - Delete Comment - Already deleted",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug3}}/comments/{{comment1}}",
+			TokenName:      "token2",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Comments - List Comments after delete",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug3}}/comments",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Comments []TestComment `json:"comments"`
+				}{
+					Comments: []TestComment{
+						TestComment{Body: "Thanks", CreatedAt: FakeTime{true}, UpdatedAt: FakeTime{true}},
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Comments - Delete Article with comments",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/{{slug3}}",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Comments - Recreate Article with same slug",
+			Method:         "POST",
+			Body:           `{"article":{"title":"Comments playground", "description":"Say something", "body":"Comments are welcome"}}`,
+			URL:            "{{APIURL}}/articles",
+			TokenName:      "token1",
+			ResponseStatus: 201,
+		},
+		&ApiTestCase{
+			Name:           "Comments - List Comments of recreated Article",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug3}}/comments",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Comments []TestComment `json:"comments"`
+				}{
+					Comments: []TestComment{},
+				}
+			},
+		},
+
+		&ApiTestCase{
+			Name:           "Profiles - Get Profile - Anonymous",
+			Method:         "GET",
+			URL:            "{{APIURL}}/profiles/{{USERNAME2}}",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Profile TestProfile
+				}{
+					Profile: TestProfile{
+						Username:  tplParams["USERNAME2"],
+						Following: false,
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Profiles - Get unknown Profile",
+			Method:         "GET",
+			URL:            "{{APIURL}}/profiles/unknown_user",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Profiles - Follow - Require Auth",
+			Method:         "POST",
+			URL:            "{{APIURL}}/profiles/{{USERNAME2}}/follow",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Profiles - Follow yourself",
+			Method:         "POST",
+			URL:            "{{APIURL}}/profiles/{{USERNAME2}}/follow",
+			TokenName:      "token2",
+			ResponseStatus: 400,
+		},
+		&ApiTestCase{
+			Name:           "Profiles - Follow",
+			Method:         "POST",
+			URL:            "{{APIURL}}/profiles/{{USERNAME2}}/follow",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Profile TestProfile
+				}{
+					Profile: TestProfile{
+						Username:  tplParams["USERNAME2"],
+						Following: true,
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Profiles - Get Profile - Follower",
+			Method:         "GET",
+			URL:            "{{APIURL}}/profiles/{{USERNAME2}}",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Profile TestProfile
+				}{
+					Profile: TestProfile{
+						Username:  tplParams["USERNAME2"],
+						Following: true,
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Profiles - Article author followed by viewer",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/{{slug2}}",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Article struct {
+						Author TestProfile `json:"user"`
+					}
+				}{
+					Article: struct {
+						Author TestProfile `json:"user"`
+					}{
+						Author: TestProfile{
+							Username:  tplParams["USERNAME2"],
+							Following: true,
+						},
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Profiles - Unfollow",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/profiles/{{USERNAME2}}/follow",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Profile TestProfile
+				}{
+					Profile: TestProfile{
+						Username:  tplParams["USERNAME2"],
+						Following: false,
+					},
+				}
+			},
+		},
+
+		&ApiTestCase{
+			Name:           "Feed - Require Auth",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Feed - Empty without followees",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles:      []TestArticleTitle{},
+					ArticlesCount: 0,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Feed - Create Article - Second user",
+			Method:         "POST",
+			Body:           `{"article":{"title":"Feed news", "description":"Fresh news", "body":"Only for followers"}}`,
+			URL:            "{{APIURL}}/articles",
+			TokenName:      "token2",
+			ResponseStatus: 201,
+		},
+		&ApiTestCase{
+			Name:           "Feed - Follow second user",
+			Method:         "POST",
+			URL:            "{{APIURL}}/profiles/{{USERNAME2}}/follow",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Feed - Followed authors articles",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Feed news"},
+						TestArticleTitle{Title: "What will be released first, Half-Life 3 or 3-rd part of golang course?"},
+					},
+					ArticlesCount: 2,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Feed - Pagination",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed?limit=1&offset=1",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "What will be released first, Half-Life 3 or 3-rd part of golang course?"},
+					},
+					ArticlesCount: 2,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Feed - Bad limit",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed?limit=-1",
+			TokenName:      "token1",
+			ResponseStatus: 400,
+		},
+
+		&ApiTestCase{
+			Name:           "Tags - All Tags",
+			Method:         "GET",
+			URL:            "{{APIURL}}/tags",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Tags []string `json:"tags"`
+				}{
+					Tags: []string{"coursera", "halflife3"},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Tags - Tag Article",
+			Method:         "PUT",
+			Body:           `{"article":{"tagList":["coursera", "news"]}}`,
+			URL:            "{{APIURL}}/articles/feed-news",
+			TokenName:      "token2",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Tags - Tags with counts",
+			Method:         "GET",
+			URL:            "{{APIURL}}/tags?withCounts=true",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Tags []TestTagCount `json:"tags"`
+				}{
+					Tags: []TestTagCount{
+						TestTagCount{Tag: "coursera", ArticlesCount: 2},
+						TestTagCount{Tag: "halflife3", ArticlesCount: 1},
+						TestTagCount{Tag: "news", ArticlesCount: 1},
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Tags - Retag Article",
+			Method:         "PUT",
+			Body:           `{"article":{"tagList":["news"]}}`,
+			URL:            "{{APIURL}}/articles/feed-news",
+			TokenName:      "token2",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Tags - Tags with counts after retag",
+			Method:         "GET",
+			URL:            "{{APIURL}}/tags?withCounts=true",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Tags []TestTagCount `json:"tags"`
+				}{
+					Tags: []TestTagCount{
+						TestTagCount{Tag: "coursera", ArticlesCount: 1},
+						TestTagCount{Tag: "halflife3", ArticlesCount: 1},
+						TestTagCount{Tag: "news", ArticlesCount: 1},
+					},
+				}
+			},
+		},
+
+		&ApiTestCase{
+			Name:           "Pagination - First page",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?limit=2",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Feed news"},
+						TestArticleTitle{Title: "Comments playground"},
+					},
+					ArticlesCount: 3,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Pagination - Second page",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?limit=2&offset=2",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "What will be released first, Half-Life 3 or 3-rd part of golang course?"},
+					},
+					ArticlesCount: 3,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Pagination - Offset out of range",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?offset=10",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles:      []TestArticleTitle{},
+					ArticlesCount: 3,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Pagination - By author",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?author={{USERNAME2}}&limit=1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Feed news"},
+					},
+					ArticlesCount: 2,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Pagination - Bad offset",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?offset=abc",
+			ResponseStatus: 400,
+		},
+
+		&ApiTestCase{
+			Name:           "Cursor - First page",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?limit=1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+					NextCursor    string             `json:"nextCursor" testdiff:"ignore"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Feed news"},
+					},
+					ArticlesCount: 3,
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "NextCursor")
+				if err != nil {
+					return err
+				}
+				if val.String() == "" {
+					return fmt.Errorf("empty next cursor")
+				}
+				tplParams["cursor1"] = val.String()
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Cursor - Create Article between pages",
+			Method:         "POST",
+			Body:           `{"article":{"title":"Cursor breaker", "description":"Created while scrolling", "body":"Must not shift pages"}}`,
+			URL:            "{{APIURL}}/articles",
+			TokenName:      "token2",
+			ResponseStatus: 201,
+		},
+		&ApiTestCase{
+			Name:           "Cursor - Second page",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?limit=1&cursor={{cursor1}}",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+					NextCursor    string             `json:"nextCursor" testdiff:"ignore"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Comments playground"},
+					},
+					ArticlesCount: 4,
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "NextCursor")
+				if err != nil {
+					return err
+				}
+				if val.String() == "" {
+					return fmt.Errorf("empty next cursor")
+				}
+				tplParams["cursor2"] = val.String()
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Cursor - Feed first page",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed?limit=1",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+					NextCursor    string             `json:"nextCursor" testdiff:"ignore"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Cursor breaker"},
+					},
+					ArticlesCount: 3,
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "NextCursor")
+				if err != nil {
+					return err
+				}
+				if val.String() == "" {
+					return fmt.Errorf("empty next cursor")
+				}
+				tplParams["feedCursor1"] = val.String()
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Cursor - Feed second page",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles/feed?limit=1&cursor={{feedCursor1}}",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+					NextCursor    string             `json:"nextCursor" testdiff:"ignore"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Feed news"},
+					},
+					ArticlesCount: 3,
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "NextCursor")
+				if err != nil {
+					return err
+				}
+				if val.String() == "" {
+					return fmt.Errorf("empty next cursor")
+				}
+				tplParams["feedCursor2"] = val.String()
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Cursor - Bad cursor",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?cursor=bad",
+			ResponseStatus: 400,
+		},
+		&ApiTestCase{
+			Name:           "Cursor - Delete Article between pages",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/articles/cursor-breaker",
+			TokenName:      "token2",
+			ResponseStatus: 200,
+		},
+
+		&ApiTestCase{
+			Name:           "Filters - Any of tags",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?tag=halflife3&tag=news",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Feed news"},
+						TestArticleTitle{Title: "What will be released first, Half-Life 3 or 3-rd part of golang course?"},
+					},
+					ArticlesCount: 2,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Filters - All of tags",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?tag=halflife3&tag=coursera&tagMode=all",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "What will be released first, Half-Life 3 or 3-rd part of golang course?"},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Filters - All of tags - No match",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?tag=halflife3&tag=news&tagMode=all",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles:      []TestArticleTitle{},
+					ArticlesCount: 0,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Filters - Author with excluded tag",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?author={{USERNAME2}}&excludeTag=news",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "What will be released first, Half-Life 3 or 3-rd part of golang course?"},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Filters - Favorite Article",
+			Method:         "POST",
+			URL:            "{{APIURL}}/articles/feed-news/favorite",
+			TokenName:      "token1",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Filters - Favorited by with author and tag",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?favorited={{USERNAME}}&author={{USERNAME2}}&tag=news",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Feed news"},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Filters - Favorited by with excluded tag",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?favorited={{USERNAME}}&excludeTag=news",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles:      []TestArticleTitle{},
+					ArticlesCount: 0,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Filters - Created in future",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?createdFrom=2100-01-01T00:00:00Z",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles:      []TestArticleTitle{},
+					ArticlesCount: 0,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Filters - Created in past",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?tag=news&createdFrom=2000-01-01T00:00:00Z&updatedTo=2100-01-01T00:00:00Z",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Feed news"},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Filters - Bad tag mode",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?tag=news&tagMode=some",
+			ResponseStatus: 400,
+		},
+		&ApiTestCase{
+			Name:           "Filters - Bad date",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?createdFrom=yesterday",
+			ResponseStatus: 400,
+		},
+
+		&ApiTestCase{
+			Name:           "Search - Word",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=Course",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "What will be released first, Half-Life 3 or 3-rd part of golang course?"},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Search - All words",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=comments+welcome",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Comments playground"},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Search - Prefix",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=play*",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Comments playground"},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Search - Ranked by relevance",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=new*",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Feed news"},
+						TestArticleTitle{Title: "What will be released first, Half-Life 3 or 3-rd part of golang course?"},
+					},
+					ArticlesCount: 2,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Search - Phrase",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=%22new+course%22",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "What will be released first, Half-Life 3 or 3-rd part of golang course?"},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Search - Phrase in wrong order",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=%22course+new%22",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles:      []TestArticleTitle{},
+					ArticlesCount: 0,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Search - With tag",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=new*&tag=halflife3",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "What will be released first, Half-Life 3 or 3-rd part of golang course?"},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Search - With author",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=new*&author={{USERNAME}}",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles:      []TestArticleTitle{},
+					ArticlesCount: 0,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Search - Update Article text",
+			Method:         "PUT",
+			Body:           `{"article":{"body":"Only for subscribers"}}`,
+			URL:            "{{APIURL}}/articles/feed-news",
+			TokenName:      "token2",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Search - Old text after update",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=followers",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles:      []TestArticleTitle{},
+					ArticlesCount: 0,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Search - New text after update",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=subscribers",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Articles      []TestArticleTitle `json:"articles"`
+					ArticlesCount int                `json:"articlesCount"`
+				}{
+					Articles: []TestArticleTitle{
+						TestArticleTitle{Title: "Feed news"},
+					},
+					ArticlesCount: 1,
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Search - Cursor is not allowed",
+			Method:         "GET",
+			URL:            "{{APIURL}}/articles?q=news&cursor={{cursor1}}",
+			ResponseStatus: 400,
+		},
+
+		&ApiTestCase{
+			Name:           "Auth - Login with wrong password",
+			Method:         "POST",
+			Body:           "{\"user\":{\"email\":\"{{EMAIL}}\", \"password\":\"wrong\"}}",
+			URL:            "{{APIURL}}/users/login",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Auth - Login with unknown email",
+			Method:         "POST",
+			Body:           "{\"user\":{\"email\":\"nobody@example.com\", \"password\":\"{{PASSWORD}}\"}}",
+			URL:            "{{APIURL}}/users/login",
+			ResponseStatus: 401,
+		},
+
+		&ApiTestCase{
+			Name:           "Sessions - Login second user from another device",
+			Method:         "POST",
+			Body:           "{\"user\":{\"email\":\"{{EMAIL2}}\", \"password\":\"{{PASSWORD}}\"}}",
+			URL:            "{{APIURL}}/users/login",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					User TestProfile
+				}{
+					User: TestProfile{
+						Email:     tplParams["EMAIL2"],
+						CreatedAt: FakeTime{true},
+						UpdatedAt: FakeTime{true},
+						Username:  tplParams["USERNAME2"],
+					},
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "User.Token")
+				if err != nil {
+					return err
+				}
+				tplParams["token3"] = val.String()
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Sessions - List",
+			Method:         "GET",
+			URL:            "{{APIURL}}/user/sessions",
+			TokenName:      "token2",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					Sessions []TestSession `json:"sessions"`
+				}{
+					Sessions: []TestSession{
+						TestSession{
+							CreatedAt:  FakeTime{true},
+							LastSeenAt: FakeTime{true},
+							UserAgent:  "Go-http-client/1.1",
+							IP:         "127.0.0.1",
+							Current:    true,
+						},
+						TestSession{
+							CreatedAt:  FakeTime{true},
+							LastSeenAt: FakeTime{true},
+							UserAgent:  "Go-http-client/1.1",
+							IP:         "127.0.0.1",
+						},
+					},
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "Sessions.ID")
+				if err != nil {
+					return err
+				}
+				tplParams["session3"] = val.Index(1).String()
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Sessions - Revoke session of other user",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/user/sessions/{{session3}}",
+			TokenName:      "token1",
+			ResponseStatus: 404,
+		},
+		&ApiTestCase{
+			Name:           "Sessions - Revoke session",
+			Method:         "DELETE",
+			URL:            "{{APIURL}}/user/sessions/{{session3}}",
+			TokenName:      "token2",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Sessions - Revoked session - No Auth",
+			Method:         "GET",
+			URL:            "{{APIURL}}/user",
+			TokenName:      "token3",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Sessions - Logout all",
+			Method:         "POST",
+			URL:            "{{APIURL}}/user/logout-all",
+			TokenName:      "token2",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Sessions - No Auth after logout all",
+			Method:         "GET",
+			URL:            "{{APIURL}}/user",
+			TokenName:      "token2",
+			ResponseStatus: 401,
+		},
+
+		&ApiTestCase{
+			Name:           "Refresh - Login second user",
+			Method:         "POST",
+			Body:           "{\"user\":{\"email\":\"{{EMAIL2}}\", \"password\":\"{{PASSWORD}}\"}}",
+			URL:            "{{APIURL}}/users/login",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					User TestProfile
+				}{
+					User: TestProfile{
+						Email:     tplParams["EMAIL2"],
+						CreatedAt: FakeTime{true},
+						UpdatedAt: FakeTime{true},
+						Username:  tplParams["USERNAME2"],
+					},
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				res := struct {
+					User TestTokenPair `json:"user"`
+				}{}
+				if err := json.Unmarshal(body, &res); err != nil {
+					return err
+				}
+				tplParams["token4"] = res.User.Token
+				tplParams["refresh4"] = res.User.RefreshToken
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Refresh - Exchange refresh token",
+			Method:         "POST",
+			Body:           `{"refreshToken":"{{refresh4}}"}`,
+			URL:            "{{APIURL}}/users/token/refresh",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &TestTokenPair{}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				pair := resp.(*TestTokenPair)
+				if pair.Token == "" || pair.RefreshToken == "" || pair.RefreshToken == tplParams["refresh4"] {
+					return fmt.Errorf("bad token pair: %s", body)
+				}
+				tplParams["token5"] = pair.Token
+				tplParams["refresh5"] = pair.RefreshToken
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Refresh - Old access token - No Auth",
+			Method:         "GET",
+			URL:            "{{APIURL}}/user",
+			TokenName:      "token4",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Refresh - New access token",
+			Method:         "GET",
+			URL:            "{{APIURL}}/user",
+			TokenName:      "token5",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Refresh - Reuse refresh token",
+			Method:         "POST",
+			Body:           `{"refreshToken":"{{refresh4}}"}`,
+			URL:            "{{APIURL}}/users/token/refresh",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Refresh - Family revoked after reuse",
+			Method:         "GET",
+			URL:            "{{APIURL}}/user",
+			TokenName:      "token5",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Refresh - Rotated token revoked after reuse",
+			Method:         "POST",
+			Body:           `{"refreshToken":"{{refresh5}}"}`,
+			URL:            "{{APIURL}}/users/token/refresh",
+			ResponseStatus: 401,
+		},
+
+		&ApiTestCase{
+			Name:           "Password - Login second user",
+			Method:         "POST",
+			Body:           "{\"user\":{\"email\":\"{{EMAIL2}}\", \"password\":\"{{PASSWORD}}\"}}",
+			URL:            "{{APIURL}}/users/login",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					User TestProfile
+				}{
+					User: TestProfile{
+						Email:     tplParams["EMAIL2"],
+						CreatedAt: FakeTime{true},
+						UpdatedAt: FakeTime{true},
+						Username:  tplParams["USERNAME2"],
+					},
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "User.Token")
+				if err != nil {
+					return err
+				}
+				tplParams["token6"] = val.String()
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Password - Login second user on other device",
+			Method:         "POST",
+			Body:           "{\"user\":{\"email\":\"{{EMAIL2}}\", \"password\":\"{{PASSWORD}}\"}}",
+			URL:            "{{APIURL}}/users/login",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					User TestProfile
+				}{
+					User: TestProfile{
+						Email:     tplParams["EMAIL2"],
+						CreatedAt: FakeTime{true},
+						UpdatedAt: FakeTime{true},
+						Username:  tplParams["USERNAME2"],
+					},
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "User.Token")
+				if err != nil {
+					return err
+				}
+				tplParams["token7"] = val.String()
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Password - Change - Wrong current password",
+			Method:         "PUT",
+			Body:           `{"user":{"currentPassword":"wrong", "newPassword":"{{PASSWORD}}_new"}}`,
+			URL:            "{{APIURL}}/user/password",
+			TokenName:      "token6",
+			ResponseStatus: 403,
+		},
+		&ApiTestCase{
+			Name:           "Password - Change - Require Auth",
+			Method:         "PUT",
+			Body:           `{"user":{"currentPassword":"{{PASSWORD}}", "newPassword":"{{PASSWORD}}_new"}}`,
+			URL:            "{{APIURL}}/user/password",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Password - Change",
+			Method:         "PUT",
+			Body:           `{"user":{"currentPassword":"{{PASSWORD}}", "newPassword":"{{PASSWORD}}_new"}}`,
+			URL:            "{{APIURL}}/user/password",
+			TokenName:      "token6",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					User TestProfile
+				}{
+					User: TestProfile{
+						Email:     tplParams["EMAIL2"],
+						CreatedAt: FakeTime{true},
+						UpdatedAt: FakeTime{true},
+						Username:  tplParams["USERNAME2"],
+					},
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				if strings.Contains(string(body), "token") {
+					return fmt.Errorf("password change must not issue tokens, have: %s", body)
+				}
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Password - Other session after change - No Auth",
+			Method:         "GET",
+			URL:            "{{APIURL}}/user",
+			TokenName:      "token7",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Password - Current session after change",
+			Method:         "GET",
+			URL:            "{{APIURL}}/user",
+			TokenName:      "token6",
+			ResponseStatus: 200,
+		},
+		&ApiTestCase{
+			Name:           "Password - Login with old password",
+			Method:         "POST",
+			Body:           "{\"user\":{\"email\":\"{{EMAIL2}}\", \"password\":\"{{PASSWORD}}\"}}",
+			URL:            "{{APIURL}}/users/login",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Password - Login with new password",
+			Method:         "POST",
+			Body:           "{\"user\":{\"email\":\"{{EMAIL2}}\", \"password\":\"{{PASSWORD}}_new\"}}",
+			URL:            "{{APIURL}}/users/login",
+			ResponseStatus: 200,
+			Expected: func() interface{} {
+				return &struct {
+					User TestProfile
+				}{
+					User: TestProfile{
+						Email:     tplParams["EMAIL2"],
+						CreatedAt: FakeTime{true},
+						UpdatedAt: FakeTime{true},
+						Username:  tplParams["USERNAME2"],
+					},
+				}
+			},
+			After: func(r *http.Response, body []byte, resp interface{}) error {
+				val, err := lookup.LookupString(resp, "User.Token")
+				if err != nil {
+					return err
+				}
+				tplParams["token8"] = val.String()
+				return nil
+			},
+		},
+		&ApiTestCase{
+			Name:           "Password - Request reset",
+			Method:         "POST",
+			Body:           `{"user":{"email":"{{EMAIL2}}"}}`,
+			URL:            "{{APIURL}}/users/password-reset",
+			ResponseStatus: 202,
+		},
+		&ApiTestCase{
+			Name:           "Password - Request reset for unknown email",
+			Method:         "POST",
+			Body:           `{"user":{"email":"nobody@example.com"}}`,
+			URL:            "{{APIURL}}/users/password-reset",
+			ResponseStatus: 202,
+		},
+		&ApiTestCase{
+			Name:           "Password - Confirm reset with bad token",
+			Method:         "POST",
+			Body:           `{"token":"bad.token", "password":"{{PASSWORD}}"}`,
+			URL:            "{{APIURL}}/users/password-reset/confirm",
+			ResponseStatus: 400,
+		},
+
+		&ApiTestCase{
+			Name:           "Verification - Verify with bad token",
+			Method:         "POST",
+			Body:           `{"token":"bad.token"}`,
+			URL:            "{{APIURL}}/users/verify",
+			ResponseStatus: 400,
+		},
+		&ApiTestCase{
+			Name:           "Verification - Resend too often",
+			Method:         "POST",
+			URL:            "{{APIURL}}/user/verify/resend",
+			TokenName:      "token1",
+			ResponseStatus: 429,
+		},
+		&ApiTestCase{
+			Name:           "Verification - Resend - Require Auth",
+			Method:         "POST",
+			URL:            "{{APIURL}}/user/verify/resend",
+			ResponseStatus: 401,
+		},
+		&ApiTestCase{
+			Name:           "Verification - Register with invalid email",
+			Method:         "POST",
+			Body:           `{"user":{"email":"not an email", "password":"{{PASSWORD}}", "username":"invalid_email"}}`,
+			URL:            "{{APIURL}}/users",
+			ResponseStatus: 422,
+		},
+
+		&ApiTestCase{
+			Name:           "Validation - Register with invalid fields",
+			Method:         "POST",
+			Body:           `{"user":{"email":"user@", "password":"", "username":"a b"}}`,
+			URL:            "{{APIURL}}/users",
+			ResponseStatus: 422,
+			Expected: func() interface{} {
+				return &struct {
+					Errors map[string][]string `json:"errors"`
+				}{
+					Errors: map[string][]string{
+						"email":    []string{"is invalid"},
+						"password": []string{"can't be empty"},
+						"username": []string{"contains forbidden characters"},
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Validation - Register taken username in other case",
+			Method:         "POST",
+			Body:           `{"user":{"email":"{{USERNAME}}_third@example.com", "password":"{{PASSWORD}}", "username":"ＧＯＬＡＮＧ"}}`,
+			URL:            "{{APIURL}}/users",
+			ResponseStatus: 422,
+			Expected: func() interface{} {
+				return &struct {
+					Errors map[string][]string `json:"errors"`
+				}{
+					Errors: map[string][]string{
+						"username": []string{"has already been taken"},
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Validation - Update to taken email in other case",
+			Method:         "PUT",
+			Body:           `{"user":{"email":" U_GOLANG@Example.com "}}`,
+			URL:            "{{APIURL}}/user",
+			TokenName:      "token8",
+			ResponseStatus: 422,
+			Expected: func() interface{} {
+				return &struct {
+					Errors map[string][]string `json:"errors"`
+				}{
+					Errors: map[string][]string{
+						"email": []string{"has already been taken"},
+					},
+				}
+			},
+		},
+		&ApiTestCase{
+			Name:           "Validation - Login with email in other case",
+			Method:         "POST",
+			Body:           "{\"user\":{\"email\":\"U_GOLANG@EXAMPLE.COM\", \"password\":\"{{PASSWORD}}\"}}",
+			URL:            "{{APIURL}}/users/login",
+			ResponseStatus: 200,
+		},
+
 		&ApiTestCase{
 			Name:           "No Auth - Current User - No Auth",
 			Method:         "GET",